@@ -0,0 +1,241 @@
+package checkout
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AllocationMode selects how the pricing rules of a basket are allocated to its items, which matters when offers overlap
+// (e.g. a multibuy on A, and a bundle containing A).
+type AllocationMode string
+
+const (
+	// AllocationGreedy applies each pricing rule as many times as possible, in order, with later rules using the remaining items.
+	// This is the default mode.
+	AllocationGreedy AllocationMode = "greedy"
+
+	// AllocationOptimal finds the combination of pricing rule applications giving the lowest checkout total.
+	//
+	// Ties are broken deterministically: leaving items unclaimed is preferred to applying a rule which does not lower the total,
+	// and rules earlier in the order are preferred to later rules.
+	//
+	// The OfferQuantity/ OfferPrice offer of a product is mandatory, as it is for AllocationGreedy: it is applied to every
+	// OfferQuantity items of the product left once the other rules are allocated, even if the offer price is more than the
+	// normal price of the items. Other rules are only applied where they lower the total.
+	AllocationOptimal AllocationMode = "optimal"
+)
+
+// MaxOptimalStates is the maximum number of basket states searched by AllocationOptimal,
+// baskets needing a larger search return ErrOptimalTooLarge rather than a possibly non optimal price.
+//
+// States are counted as they are visited, so this also bounds the depth of the search (e.g. for a large quantity of one product).
+const MaxOptimalStates = 100000
+
+// ErrOptimalTooLarge is returned when a basket needs a search of more than MaxOptimalStates states to allocate optimally.
+var ErrOptimalTooLarge = errors.New("basket is too large for optimal allocation, use greedy allocation")
+
+// ParseAllocationMode returns the AllocationMode for a name, with an empty name giving the default AllocationGreedy.
+//
+// An error is returned if the name is not a known allocation mode.
+func ParseAllocationMode(name string) (AllocationMode, error) {
+	switch mode := AllocationMode(name); mode {
+	case "":
+		return AllocationGreedy, nil
+	case AllocationGreedy, AllocationOptimal:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown allocation mode %q, expected %q or %q", name, AllocationGreedy, AllocationOptimal)
+	}
+}
+
+// applyPricingRules applies the rules from basketRules to the basket using an allocation mode,
+// returning an adjustment for each rule which could be applied, in rule order.
+func applyPricingRules(basket *Basket, mode AllocationMode) ([]Adjustment, error) {
+
+	mode, err := ParseAllocationMode(string(mode))
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := basketRules(basket)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == AllocationOptimal {
+		return allocateOptimal(basket, rules)
+	}
+
+	return allocateGreedy(basket, rules)
+}
+
+// allocateGreedy applies each rule as many times as possible, in order.
+func allocateGreedy(basket *Basket, rules []PricingRule) ([]Adjustment, error) {
+
+	adjustments := []Adjustment{}
+
+	for _, rule := range rules {
+		adj, err := rule.Apply(basket, NoLimit)
+		if err != nil {
+			return nil, err
+		}
+		if adj.Applications > 0 {
+			adjustments = append(adjustments, adj)
+		}
+	}
+
+	return adjustments, nil
+}
+
+// optimalStep is the best allocation found from a basket state,
+// being the total of its adjustments, and the first rule application of the allocation.
+type optimalStep struct {
+	total int64
+	rule  int // index of the rule applied first, -1 if no rule is applied
+	adj   Adjustment
+	next  *Basket // basket after the rule is applied
+}
+
+// optimalSearch holds the rules being allocated, the best allocation found from each basket state searched,
+// and the number of states visited, including those whose search has not finished.
+type optimalSearch struct {
+	rules   []PricingRule
+	memo    map[string]optimalStep
+	visited int
+}
+
+// allocateOptimal searches every combination of single rule applications for the one with the lowest total,
+// merging the applications of each rule into a single adjustment.
+func allocateOptimal(basket *Basket, rules []PricingRule) ([]Adjustment, error) {
+
+	search := optimalSearch{rules: rules, memo: map[string]optimalStep{}}
+
+	step, err := search.best(basket)
+	if err != nil {
+		return nil, err
+	}
+
+	// follow the best allocation, merging the applications of each rule
+	merged := make([]Adjustment, len(rules))
+	for ; step.rule != -1; step = search.memo[step.next.state()] {
+		if merged[step.rule], err = mergeAdjustments(merged[step.rule], step.adj); err != nil {
+			return nil, basket.overflowError(basket.firstClaimed(step.adj), step.adj.Rule+" adjustment")
+		}
+		basket.remaining = step.next.remaining
+	}
+
+	adjustments := []Adjustment{}
+	for _, adj := range merged {
+		if adj.Applications > 0 {
+			adjustments = append(adjustments, adj)
+		}
+	}
+
+	return adjustments, nil
+}
+
+// best returns the best allocation from a basket state, trying each rule applied once, followed by the best allocation from the resulting state.
+func (s *optimalSearch) best(basket *Basket) (optimalStep, error) {
+
+	state := basket.state()
+	if step, ok := s.memo[state]; ok {
+		return step, nil
+	}
+	if s.visited >= MaxOptimalStates {
+		return optimalStep{}, ErrOptimalTooLarge
+	}
+	s.visited++
+
+	// the best step applying a rule, and whether a mandatory rule can be applied, so the search cannot stop at this state
+	best := optimalStep{rule: -1}
+	found, mandatory := false, false
+
+	for i, rule := range s.rules {
+		next := basket.clone()
+		adj, err := rule.Apply(next, 1)
+		if err != nil {
+			return optimalStep{}, err
+		}
+		if adj.Applications == 0 {
+			continue
+		}
+
+		step, err := s.best(next)
+		if err != nil {
+			return optimalStep{}, err
+		}
+		var c checked
+		total := c.add(adj.Amount.Amount, step.total)
+		if c.overflow {
+			return optimalStep{}, basket.overflowError(basket.firstClaimed(adj), "optimal allocation total")
+		}
+		if !found || total < best.total {
+			best = optimalStep{total: total, rule: i, adj: adj, next: next}
+			found = true
+		}
+		mandatory = mandatory || mandatoryRule(rule)
+	}
+
+	// stop here, leaving the remaining items unclaimed, unless a rule lowers the total or must be applied
+	if !mandatory && best.total >= 0 {
+		best = optimalStep{rule: -1}
+	}
+
+	s.memo[state] = best
+
+	return best, nil
+}
+
+// mandatoryRule returns true if a rule must be applied wherever it can be, being the OfferQuantity/ OfferPrice offer of a product.
+func mandatoryRule(rule PricingRule) bool {
+	multiBuy, ok := rule.(multiBuyRule)
+	return ok && multiBuy.legacy
+}
+
+// mergeAdjustments combines two adjustments made by the same rule.
+//
+// An error wrapping ErrOverflow is returned if the combined amount overflows.
+func mergeAdjustments(a Adjustment, b Adjustment) (Adjustment, error) {
+	if a.Applications == 0 {
+		return b, nil
+	}
+
+	claimed := map[string]int{}
+	for code, qty := range a.Claimed {
+		claimed[code] += qty
+	}
+	for code, qty := range b.Claimed {
+		claimed[code] += qty
+	}
+
+	a.Applications += b.Applications
+	a.Claimed = claimed
+	amount, err := a.Amount.Add(b.Amount)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	a.Amount = amount
+
+	return a, nil
+}
+
+// clone returns a copy of the basket, which can be claimed from without affecting the original.
+func (b *Basket) clone() *Basket {
+	remaining := make(map[string]int, len(b.remaining))
+	for code, qty := range b.remaining {
+		remaining[code] = qty
+	}
+	return &Basket{products: b.products, currency: b.currency, codes: b.codes, lines: b.lines, gross: b.gross, measure: b.measure, quantity: b.quantity, remaining: remaining}
+}
+
+// state returns a string identifying the remaining quantities of the basket.
+func (b *Basket) state() string {
+	var sb strings.Builder
+	for _, code := range b.codes {
+		sb.WriteString(strconv.Itoa(b.remaining[code]))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
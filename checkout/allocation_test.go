@@ -0,0 +1,317 @@
+package checkout_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// overlappingProducts is a products map where the offers overlap, so the allocation of rules changes the checkout price.
+var overlappingProducts = map[string]checkout.Product{
+	"A": {Price: 50, OfferQuantity: 3, OfferPrice: 100, Rules: []checkout.RuleSpec{
+		rule("bundle", `{"Items": {"A": 1, "B": 1}, "Price": 60}`),
+		rule("percentoff", `{"Percent": 10}`),
+	}},
+	"B": {Price: 35, Groups: []string{"drinks"}, Rules: []checkout.RuleSpec{
+		rule("buyxgety", `{"Buy": 2, "Get": 1, "GetCode": "C"}`),
+		rule("groupmultibuy", `{"Group": "drinks", "Quantity": 2, "Price": 50}`),
+	}},
+	"C": {Price: 25, Groups: []string{"drinks"}, Rules: []checkout.RuleSpec{
+		rule("groupmultibuy", `{"Group": "drinks", "Quantity": 2, "Price": 50}`),
+	}},
+	"D": {Price: 12, Rules: []checkout.RuleSpec{
+		rule("bundle", `{"Items": {"A": 1, "D": 2}, "Price": 55}`),
+	}},
+}
+
+// Test_PriceCheckoutAllocation tests the PriceCheckout function with each AllocationMode.
+//
+// It passes a slice of CheckoutLine, the overlappingProducts map and PricingOptions to the function,
+// checking for the correct expected checkout price, and whether or not an error is expected.
+func Test_PriceCheckoutAllocation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		allocation    checkout.AllocationMode
+		expected      int
+		expErr        bool
+	}{
+		{
+			"1: greedy allocation uses multibuy before bundle",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 3}},
+			checkout.AllocationGreedy,
+			185,
+			false,
+		},
+		{
+			"2: optimal allocation uses bundles",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 3}},
+			checkout.AllocationOptimal,
+			180,
+			false,
+		},
+		{
+			"3: default allocation is greedy",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 3}},
+			"",
+			185,
+			false,
+		},
+		{
+			"4: optimal allocation with no overlapping offers",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}},
+			checkout.AllocationOptimal,
+			100,
+			false,
+		},
+		{
+			"5: optimal allocation with no checkout lines",
+			[]checkout.CheckoutLine{},
+			checkout.AllocationOptimal,
+			0,
+			false,
+		},
+		{
+			"6: unknown allocation mode",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}},
+			"fake",
+			0,
+			true,
+		},
+		{
+			"7: basket too large for optimal allocation",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 2000}, {Code: "B", Quantity: 2000}, {Code: "D", Quantity: 2000}},
+			checkout.AllocationOptimal,
+			0,
+			true,
+		},
+		{
+			"8: quantity too large for optimal allocation",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 999991023}},
+			checkout.AllocationOptimal,
+			0,
+			true,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			receipt, err := checkout.PriceCheckout(testCase.checkoutLines, overlappingProducts, checkout.PricingOptions{Allocation: testCase.allocation})
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			// compare result val to expected val
+			if receipt.Total.Amount != int64(testCase.expected) {
+				t.Errorf("expected checkout price of: %v, got checkout price of: %v", testCase.expected, receipt.Total)
+			}
+		})
+	}
+}
+
+// Test_OptimalAllocationTieBreak checks that when two rules give the same saving, the earlier rule is allocated.
+func Test_OptimalAllocationTieBreak(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50, Rules: []checkout.RuleSpec{
+			rule("amountoff", `{"Amount": 10}`),
+			rule("percentoff", `{"Percent": 20}`),
+		}},
+	}
+
+	receipt, err := checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "A", Quantity: 2}}, products, checkout.PricingOptions{Allocation: checkout.AllocationOptimal})
+	if err != nil {
+		t.Fatalf("expected no error, got err: %s", err)
+	}
+	if len(receipt.Adjustments) != 1 || receipt.Adjustments[0].Rule != "amountoff" || receipt.Adjustments[0].Applications != 2 {
+		t.Errorf("expected single amountoff adjustment with 2 applications, got adjustments: %v", receipt.Adjustments)
+	}
+	if receipt.Total.Amount != 80 {
+		t.Errorf("expected checkout price of: 80, got checkout price of: %v", receipt.Total)
+	}
+}
+
+// Test_AllocationMandatoryOffer checks that the OfferQuantity/ OfferPrice offer of a product is applied by both allocation modes,
+// even when the offer price is more than the normal price of the items, so both modes price a catalog with no Rules the same.
+func Test_AllocationMandatoryOffer(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 10, OfferQuantity: 3, OfferPrice: 40},
+	}
+
+	for _, mode := range []checkout.AllocationMode{checkout.AllocationGreedy, checkout.AllocationOptimal} {
+		receipt, err := checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "A", Quantity: 4}}, products, checkout.PricingOptions{Allocation: mode})
+		if err != nil {
+			t.Fatalf("expected no error, got err: %s", err)
+		}
+		// 3 for 40, with the remaining item at its normal price
+		if receipt.Total.Amount != 50 {
+			t.Errorf("expected %s checkout price of: 50, got checkout price of: %v", mode, receipt.Total)
+		}
+	}
+}
+
+// positiveProducts is a products map with a multibuy costing more than its items, and a product in two overlapping groups.
+var positiveProducts = map[string]checkout.Product{
+	"A": {Price: 10, OfferQuantity: 3, OfferPrice: 40, Groups: []string{"fruit"}, Rules: []checkout.RuleSpec{
+		rule("bundle", `{"Items": {"A": 1, "B": 1}, "Price": 25}`),
+	}},
+	"B": {Price: 20, Groups: []string{"fruit", "snacks"}, Rules: []checkout.RuleSpec{
+		rule("groupmultibuy", `{"Group": "fruit", "Quantity": 2, "Price": 22}`),
+	}},
+	"C": {Price: 15, Groups: []string{"snacks"}, Rules: []checkout.RuleSpec{
+		rule("groupmultibuy", `{"Group": "snacks", "Quantity": 2, "Price": 28}`),
+	}},
+}
+
+// deal is a set of items the offers of a products map price together at price, or for a group offer,
+// the size most expensive items left of the products in group, listed from the most to least expensive.
+type deal struct {
+	items map[string]int
+	group []string
+	size  int
+	price int
+}
+
+// itemsFrom returns the items of a deal, given the items left, and false if the deal cannot be made from them.
+// Group offers are only made where they save, as group offers are only applied while the offer price is less than the normal price.
+func (d deal) itemsFrom(products map[string]checkout.Product, left map[string]int) (map[string]int, bool) {
+	if d.group == nil {
+		for code, qty := range d.items {
+			if left[code] < qty {
+				return nil, false
+			}
+		}
+		return d.items, true
+	}
+
+	items := map[string]int{}
+	need := d.size
+	for _, code := range d.group {
+		take := left[code]
+		if take > need {
+			take = need
+		}
+		if take > 0 {
+			items[code] = take
+		}
+		need -= take
+	}
+	return items, need == 0 && normalPrice(products, items) > d.price
+}
+
+// normalPrice returns the price of a set of items with no offers applied.
+func normalPrice(products map[string]checkout.Product, items map[string]int) int {
+	price := 0
+	for code, qty := range items {
+		price += qty * products[code].Price
+	}
+	return price
+}
+
+// overlappingDeals lists the deals of the overlappingProducts map, written out by hand from its offers.
+var overlappingDeals = []deal{
+	{items: map[string]int{"A": 3}, price: 100},        // A multibuy, 3 for 100
+	{items: map[string]int{"A": 1, "B": 1}, price: 60}, // A + B bundle
+	{items: map[string]int{"A": 1}, price: 45},         // 10% off A
+	{items: map[string]int{"B": 2, "C": 1}, price: 70}, // buy 2 B get C free
+	{group: []string{"B", "C"}, size: 2, price: 50},    // any 2 drinks for 50
+	{items: map[string]int{"A": 1, "D": 2}, price: 55}, // A + 2 D bundle
+}
+
+// positiveDeals lists the deals of the positiveProducts map, including the A multibuy which costs more than its items,
+// and group offers overlapping on B.
+var positiveDeals = []deal{
+	{items: map[string]int{"A": 3}, price: 40},         // A multibuy, 3 for 40
+	{items: map[string]int{"A": 1, "B": 1}, price: 25}, // A + B bundle
+	{group: []string{"B", "A"}, size: 2, price: 22},    // any 2 fruit for 22
+	{group: []string{"B", "C"}, size: 2, price: 28},    // any 2 snacks for 28
+}
+
+// Test_OptimalAllocationBruteForce compares the optimal allocation against a brute force search of every assignment of
+// the basket's items to deals, written out by hand from the offers of the products rather than using the pricing rules, for small baskets.
+//
+// The OfferQuantity/ OfferPrice offer of a product is mandatory, so an assignment is only valid if it leaves fewer than
+// OfferQuantity items of that product at their normal price.
+func Test_OptimalAllocationBruteForce(t *testing.T) {
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		products      map[string]checkout.Product
+		deals         []deal
+	}{
+		{"1: multibuy and bundle", []checkout.CheckoutLine{{Code: "A", Quantity: 4}, {Code: "B", Quantity: 2}}, overlappingProducts, overlappingDeals},
+		{"2: buy x get y and group deal", []checkout.CheckoutLine{{Code: "B", Quantity: 4}, {Code: "C", Quantity: 2}}, overlappingProducts, overlappingDeals},
+		{"3: every rule", []checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 2}, {Code: "C", Quantity: 1}, {Code: "D", Quantity: 2}}, overlappingProducts, overlappingDeals},
+		{"4: bundles sharing a product", []checkout.CheckoutLine{{Code: "D", Quantity: 3}, {Code: "A", Quantity: 3}, {Code: "B", Quantity: 1}}, overlappingProducts, overlappingDeals},
+		{"5: single product", []checkout.CheckoutLine{{Code: "A", Quantity: 5}}, overlappingProducts, overlappingDeals},
+		{"6: multibuy costing more than its items", []checkout.CheckoutLine{{Code: "A", Quantity: 3}}, positiveProducts, positiveDeals},
+		{"7: multibuy costing more avoided by a bundle", []checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 1}}, positiveProducts, positiveDeals},
+		{"8: overlapping groups", []checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "B", Quantity: 3}, {Code: "C", Quantity: 2}}, positiveProducts, positiveDeals},
+		{"9: overlapping groups and multibuy", []checkout.CheckoutLine{{Code: "A", Quantity: 4}, {Code: "B", Quantity: 2}, {Code: "C", Quantity: 1}}, positiveProducts, positiveDeals},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			receipt, err := checkout.PriceCheckout(testCase.checkoutLines, testCase.products, checkout.PricingOptions{Allocation: checkout.AllocationOptimal})
+			if err != nil {
+				t.Fatalf("expected no error, got err: %s", err)
+			}
+
+			items := map[string]int{}
+			for _, cL := range testCase.checkoutLines {
+				items[cL.Code] += cL.Quantity
+			}
+			expected := bruteForcePrice(testCase.products, testCase.deals, items, map[string]int{})
+			if receipt.Total.Amount != int64(expected) {
+				t.Errorf("expected brute force checkout price of: %v, got optimal checkout price of: %v", expected, receipt.Total)
+			}
+
+			greedy, err := checkout.GetCheckoutPrice(testCase.checkoutLines, testCase.products)
+			if err != nil {
+				t.Fatalf("expected no error, got err: %s", err)
+			}
+			if int64(greedy) < receipt.Total.Amount {
+				t.Errorf("expected greedy checkout price of: %v to be no lower than optimal checkout price of: %v", greedy, receipt.Total)
+			}
+		})
+	}
+}
+
+// bruteForcePrice returns the lowest price of the items, trying every deal each time it can be made from the items left,
+// with the items no deal is assigned to at their normal price. -1 is returned if every assignment leaves OfferQuantity or more
+// items of a product with an OfferQuantity/ OfferPrice offer at their normal price.
+func bruteForcePrice(products map[string]checkout.Product, deals []deal, items map[string]int, memo map[string]int) int {
+
+	key := fmt.Sprint(items)
+	if price, ok := memo[key]; ok {
+		return price
+	}
+
+	best := normalPrice(products, items)
+	for code, qty := range items {
+		if offer := products[code].OfferQuantity; offer > 0 && qty >= offer {
+			best = -1
+		}
+	}
+
+	for _, d := range deals {
+		dealItems, ok := d.itemsFrom(products, items)
+		if !ok {
+			continue
+		}
+		left := map[string]int{}
+		for code, qty := range items {
+			left[code] = qty - dealItems[code]
+		}
+		if price := bruteForcePrice(products, deals, left, memo); price != -1 && (best == -1 || d.price+price < best) {
+			best = d.price + price
+		}
+	}
+
+	memo[key] = best
+	return best
+}
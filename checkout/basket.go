@@ -0,0 +1,216 @@
+package checkout
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Basket holds the total quantity of each product in a checkout, tracking how much of each quantity
+// has not yet been claimed by a PricingRule.
+//
+// Checkout lines sharing a product code are merged, product codes are kept in the order they first appear.
+// Products sold by weight or volume have no quantity to be claimed, only the normal price of their measured lines.
+// Every product in a basket must be priced in the same currency,
+// and the normal price of the total quantity of each product must fit in an int64.
+type Basket struct {
+	products  map[string]Product
+	currency  string
+	codes     []string
+	lines     map[string]int     // index of the first checkout line of each product code
+	gross     map[string]int64   // normal price of the checkout lines of each product code
+	measure   map[string]Measure // total measure of each product code sold by weight or volume
+	quantity  map[string]int
+	remaining map[string]int
+}
+
+// NewBasket creates a Basket from a slice of CheckoutLine and a map of [productCode]Product.
+//
+// A *LineError naming the line is returned if a checkout line quantity or measure is negative (wrapping ErrNegativeQuantity),
+// if a checkout line product code is not in the products map (wrapping ErrUnknownProduct),
+// if a line of a product sold each has a measure, or a line of a product sold by weight or volume has a quantity,
+// or if the currency of a product is unsupported or different from the currency of the products before it (wrapping ErrCurrencyMismatch).
+// An *OverflowError naming the line is returned if the total quantity of a product, or its normal price, overflows.
+func NewBasket(cLSlice []CheckoutLine, products map[string]Product) (*Basket, error) {
+
+	basket := newBasket(products)
+
+	// loop over checkout lines, merging quantities of lines with the same product code
+	for i, cL := range cLSlice {
+		if err := basket.add(i, cL); err != nil {
+			return nil, err
+		}
+	}
+
+	return basket, nil
+}
+
+// collectBasket creates a Basket from the checkout lines which can be added to it (see addCollected),
+// returning a *LineErrors holding the error of each line which could not, or nil if every line was added.
+func collectBasket(cLSlice []CheckoutLine, products map[string]Product) (*Basket, *LineErrors) {
+
+	basket := newBasket(products)
+	lineErrs := &LineErrors{}
+
+	for i, cL := range cLSlice {
+		if err := basket.addCollected(i, cL); err != nil {
+			lineErrs.Errors = append(lineErrs.Errors, err)
+		}
+	}
+
+	if len(lineErrs.Errors) == 0 {
+		return basket, nil
+	}
+	return basket, lineErrs
+}
+
+// newBasket returns an empty Basket of products.
+func newBasket(products map[string]Product) *Basket {
+	return &Basket{
+		products:  products,
+		currency:  DefaultCurrency,
+		lines:     map[string]int{},
+		gross:     map[string]int64{},
+		measure:   map[string]Measure{},
+		quantity:  map[string]int{},
+		remaining: map[string]int{},
+	}
+}
+
+// add merges checkout line i into the basket, returning the errors described by NewBasket.
+// If an error is returned the basket is left unchanged, so later lines can still be added.
+func (b *Basket) add(i int, cL CheckoutLine) error {
+
+	lineError := func(err error) error {
+		return &LineError{Index: i, Code: cL.Code, Err: err}
+	}
+
+	// check for invalid checkout quantity.
+	if cL.Quantity < 0 {
+		return lineError(ErrNegativeQuantity)
+	}
+	if cL.Measure < 0 {
+		return lineError(fmt.Errorf("%w: measure %v", ErrNegativeQuantity, cL.Measure))
+	}
+	prod, ok := b.products[cL.Code]
+	if !ok {
+		return lineError(ErrUnknownProduct)
+	}
+	// check the line is counted or measured to match the product
+	if prod.Measured() && cL.Quantity != 0 {
+		return lineError(fmt.Errorf("product is sold by %s, checkout line must give a measure rather than a quantity", prod.unit()))
+	}
+	if !prod.Measured() && cL.Measure != 0 {
+		return lineError(errors.New("product is sold each, checkout line must give a quantity rather than a measure"))
+	}
+	// check the currency of the product matches the basket
+	if _, ok := CurrencyExponent(prod.currency()); !ok {
+		return lineError(fmt.Errorf("unsupported currency %q", prod.currency()))
+	}
+	if len(b.codes) > 0 && prod.currency() != b.currency {
+		return lineError(fmt.Errorf("%w: product is priced in %s, basket is priced in %s", ErrCurrencyMismatch, prod.currency(), b.currency))
+	}
+
+	// check the total quantity of the product, and its normal price, can be represented
+	var c checked
+	quantity := c.add(int64(b.quantity[cL.Code]), int64(cL.Quantity))
+	measure := c.add(int64(b.measure[cL.Code]), int64(cL.Measure))
+	lineGross, ok := lineGross(cL, prod)
+	gross := c.add(b.gross[cL.Code], lineGross)
+	if c.overflow || !ok || int64(int(quantity)) != quantity {
+		return &OverflowError{Line: i, Code: cL.Code, Amount: "line total"}
+	}
+
+	if _, ok := b.quantity[cL.Code]; !ok {
+		if len(b.codes) == 0 {
+			b.currency = prod.currency()
+		}
+		b.codes = append(b.codes, cL.Code)
+		b.lines[cL.Code] = i
+	}
+	b.quantity[cL.Code] = int(quantity)
+	b.remaining[cL.Code] = int(quantity)
+	b.gross[cL.Code] = gross
+	if measure != 0 {
+		b.measure[cL.Code] = Measure(measure)
+	}
+
+	return nil
+}
+
+// addCollected merges checkout line i into the basket as add does, also returning a *LineError if the pricing rules of its product
+// cannot be built (e.g. a negative OfferQuantity or an unknown rule type), so when collecting errors a product with invalid rules
+// only rejects its own lines, rather than failing the checkout when the rules are applied.
+func (b *Basket) addCollected(i int, cL CheckoutLine) error {
+
+	// the rules of a product code already in the basket have been built
+	if prod, ok := b.products[cL.Code]; ok {
+		if _, added := b.quantity[cL.Code]; !added {
+			if _, err := prod.pricingRules(cL.Code); err != nil {
+				return &LineError{Index: i, Code: cL.Code, Err: err}
+			}
+		}
+	}
+
+	return b.add(i, cL)
+}
+
+// Codes returns the product codes in the basket, in the order they first appear in the checkout lines.
+func (b *Basket) Codes() []string {
+	return append([]string{}, b.codes...)
+}
+
+// Currency returns the ISO 4217 currency code the products in the basket are priced in,
+// an empty basket is priced in DefaultCurrency.
+func (b *Basket) Currency() string {
+	return b.currency
+}
+
+// money returns an amount in the minor units of the basket currency as Money.
+func (b *Basket) money(amount int64) Money {
+	return Money{Amount: amount, Currency: b.currency}
+}
+
+// overflowError returns an *OverflowError for an amount calculated for a product code, naming the first checkout line of the code.
+func (b *Basket) overflowError(code string, amount string) error {
+	return &OverflowError{Line: b.lines[code], Code: code, Amount: amount}
+}
+
+// firstClaimed returns the product code claimed by an Adjustment which appears first in the basket.
+func (b *Basket) firstClaimed(adj Adjustment) string {
+	for _, code := range b.codes {
+		if _, ok := adj.Claimed[code]; ok {
+			return code
+		}
+	}
+	return ""
+}
+
+// Product returns the Product for a product code, and whether or not the code was found in the products map.
+func (b *Basket) Product(code string) (Product, bool) {
+	prod, ok := b.products[code]
+	return prod, ok
+}
+
+// Quantity returns the total quantity of a product code in the basket.
+func (b *Basket) Quantity(code string) int {
+	return b.quantity[code]
+}
+
+// Remaining returns the quantity of a product code in the basket which has not yet been claimed.
+func (b *Basket) Remaining(code string) int {
+	return b.remaining[code]
+}
+
+// Claim marks quantity items of a product code as used by a PricingRule, so they cannot be used by another rule.
+//
+// An error is returned if quantity is negative, or more than the remaining quantity of the product code.
+func (b *Basket) Claim(code string, quantity int) error {
+	if quantity < 0 {
+		return errors.New("claimed quantity cannot be negative")
+	}
+	if quantity > b.remaining[code] {
+		return fmt.Errorf("cannot claim %d of product %q, only %d remaining", quantity, code, b.remaining[code])
+	}
+	b.remaining[code] -= quantity
+	return nil
+}
@@ -0,0 +1,135 @@
+package checkout_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_NewBasket tests the NewBasket function.
+//
+// It passes a slice of CheckoutLine and a map of [productCode]Product to the function,
+// checking the product codes/ quantities in the returned basket, and whether or not an error is expected.
+func Test_NewBasket(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50},
+		"B": {Price: 35},
+		"C": {Price: 25},
+	}
+
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		expCodes      []string
+		expQuantities []int
+		expErr        bool
+	}{
+		{
+			"1: one line per product",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 1}, {Code: "C", Quantity: 0}},
+			[]string{"A", "B", "C"},
+			[]int{3, 1, 0},
+			false,
+		},
+		{
+			"2: lines with repeated product codes are merged",
+			[]checkout.CheckoutLine{{Code: "B", Quantity: 2}, {Code: "A", Quantity: 1}, {Code: "B", Quantity: 4}},
+			[]string{"B", "A"},
+			[]int{6, 1},
+			false,
+		},
+		{
+			"3: no checkout lines",
+			[]checkout.CheckoutLine{},
+			[]string{},
+			[]int{},
+			false,
+		},
+		{
+			"4: negative checkout line quantity",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: -1}},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"5: product code not in products map",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "E", Quantity: 1}},
+			nil,
+			nil,
+			true,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			basket, err := checkout.NewBasket(testCase.checkoutLines, products)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Fatalf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			if err != nil {
+				return
+			}
+			// compare codes and quantities to expected vals
+			if codes := basket.Codes(); !reflect.DeepEqual(codes, testCase.expCodes) {
+				t.Errorf("expected codes: %v, got codes: %v", testCase.expCodes, codes)
+			}
+			for i, code := range testCase.expCodes {
+				if qty := basket.Quantity(code); qty != testCase.expQuantities[i] {
+					t.Errorf("expected quantity of %s: %v, got: %v", code, testCase.expQuantities[i], qty)
+				}
+				if remaining := basket.Remaining(code); remaining != testCase.expQuantities[i] {
+					t.Errorf("expected remaining quantity of %s: %v, got: %v", code, testCase.expQuantities[i], remaining)
+				}
+			}
+		})
+	}
+}
+
+// Test_BasketClaim tests the Claim method of Basket, checking the remaining quantity after each claim,
+// and whether or not an error is expected.
+func Test_BasketClaim(t *testing.T) {
+	testCases := []struct {
+		name         string
+		claim        int
+		expRemaining int
+		expErr       bool
+	}{
+		{"1: claim part of quantity", 3, 2, false},
+		{"2: claim all of quantity", 5, 0, false},
+		{"3: claim nothing", 0, 5, false},
+		{"4: claim more than quantity", 6, 5, true},
+		{"5: negative claim", -1, 5, true},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			basket, err := checkout.NewBasket(
+				[]checkout.CheckoutLine{{Code: "A", Quantity: 5}},
+				map[string]checkout.Product{"A": {Price: 10}},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = basket.Claim("A", testCase.claim)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			// compare remaining quantity to expected val
+			if remaining := basket.Remaining("A"); remaining != testCase.expRemaining {
+				t.Errorf("expected remaining quantity: %v, got: %v", testCase.expRemaining, remaining)
+			}
+			// total quantity is unchanged by claims
+			if qty := basket.Quantity("A"); qty != 5 {
+				t.Errorf("expected quantity: 5, got: %v", qty)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type (
+	// BasketRule is implemented by each type of adjustment made to the whole basket, once its items have been priced
+	// and its pricing rules applied (e.g. spend 200, get 20 off).
+	//
+	// Adjust is passed the basket and the Receipt so far, with its Total being the running total of the basket,
+	// and returns the Adjustment made to the total. If the rule does not apply, the returned Adjustment has 0 Applications.
+	// The Claimed quantities of the Adjustment are the products discounted by the rule, used to divide it between tax classes,
+	// if none are claimed it is divided between every product in the basket.
+	BasketRule interface {
+		Adjust(basket *Basket, receipt Receipt) (Adjustment, error)
+	}
+
+	// BasketRuleFactory builds a BasketRule from the JSON parameters of a RuleSpec.
+	BasketRuleFactory func(params json.RawMessage) (BasketRule, error)
+)
+
+var (
+	// basketRuleFactoriesMu guards basketRuleFactories
+	basketRuleFactoriesMu sync.RWMutex
+
+	// basketRuleFactories maps basket rule type names to the BasketRuleFactory used to build them
+	basketRuleFactories = map[string]BasketRuleFactory{
+		"spendamountoff":  newSpendAmountOffRule,
+		"spendpercentoff": newSpendPercentOffRule,
+	}
+)
+
+// RegisterBasketRule makes a type of BasketRule available to be referenced by name from the BasketRules of PricingOptions.
+//
+// An error is returned if ruleType is empty, factory is nil, or a basket rule is already registered with the same name.
+func RegisterBasketRule(ruleType string, factory BasketRuleFactory) error {
+	if ruleType == "" {
+		return errors.New("rule type cannot be empty")
+	}
+	if factory == nil {
+		return errors.New("rule factory cannot be nil")
+	}
+
+	basketRuleFactoriesMu.Lock()
+	defer basketRuleFactoriesMu.Unlock()
+
+	if _, ok := basketRuleFactories[ruleType]; ok {
+		return fmt.Errorf("basket rule type %q is already registered", ruleType)
+	}
+	basketRuleFactories[ruleType] = factory
+
+	return nil
+}
+
+// NewBasketRule builds the BasketRule referenced by a RuleSpec.
+//
+// An error is returned if no basket rule is registered with the type name of the RuleSpec, or if its parameters are invalid.
+func NewBasketRule(spec RuleSpec) (BasketRule, error) {
+
+	basketRuleFactoriesMu.RLock()
+	factory, ok := basketRuleFactories[spec.Type]
+	basketRuleFactoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown basket rule type %q", spec.Type)
+	}
+
+	rule, err := factory(spec.Params)
+	if err != nil {
+		return nil, fmt.Errorf("%s basket rule: %w", spec.Type, err)
+	}
+
+	return rule, nil
+}
+
+// applyBasketRules builds and applies each basket rule in order, with each rule seeing the total after the rules before it.
+//
+// Returned are the adjustments of the rules which could be applied, and the receipt total after those adjustments.
+func applyBasketRules(basket *Basket, receipt Receipt, specs []RuleSpec) ([]Adjustment, Money, error) {
+
+	adjustments := []Adjustment{}
+
+	for _, spec := range specs {
+		rule, err := NewBasketRule(spec)
+		if err != nil {
+			return nil, Money{}, err
+		}
+
+		adj, err := rule.Adjust(basket, receipt)
+		if err != nil {
+			return nil, Money{}, err
+		}
+		if adj.Applications > 0 {
+			adjustments = append(adjustments, adj)
+			receipt.Total, err = receipt.Total.Add(adj.Amount)
+			if err != nil {
+				return nil, Money{}, fmt.Errorf("%s basket rule: %w", spec.Type, err)
+			}
+		}
+	}
+
+	return adjustments, receipt.Total, nil
+}
+
+// SpendExclusions lists the products which do not count toward the spend of a basket rule, and are not discounted by it,
+// either by product code (Exclude) or by group (ExcludeGroups).
+type SpendExclusions struct {
+	Exclude       []string
+	ExcludeGroups []string
+}
+
+// excludes returns whether or not a product is excluded.
+func (e SpendExclusions) excludes(code string, prod Product) bool {
+	for _, excluded := range e.Exclude {
+		if excluded == code {
+			return true
+		}
+	}
+	for _, group := range e.ExcludeGroups {
+		if prod.inGroup(group) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimed returns the quantity of each product in the basket which is not excluded, being the products discounted by a basket rule.
+// Products sold by weight or volume are claimed with a quantity of 0.
+func (e SpendExclusions) claimed(basket *Basket) map[string]int {
+	claimed := map[string]int{}
+	for _, code := range basket.Codes() {
+		if prod, _ := basket.Product(code); (basket.Quantity(code) > 0 || prod.Measured()) && !e.excludes(code, prod) {
+			claimed[code] = basket.Quantity(code)
+		}
+	}
+	return claimed
+}
+
+// QualifyingSpend returns the spend of a basket which counts toward a basket rule, being the running total of the receipt
+// less the normal price of excluded products, and less the share of each pricing rule adjustment taken off excluded products.
+// An adjustment claiming both excluded and eligible products is divided between them as for tax, in proportion to the
+// normal price of the items it claimed.
+//
+// An *OverflowError naming an excluded product is returned if the spend overflows.
+func (e SpendExclusions) QualifyingSpend(basket *Basket, receipt Receipt) (Money, error) {
+
+	var c checked
+	spend := receipt.Total
+
+	for _, code := range basket.Codes() {
+		if prod, _ := basket.Product(code); e.excludes(code, prod) {
+			spend.Amount = c.sub(spend.Amount, basket.gross[code])
+			if c.overflow {
+				return Money{}, basket.overflowError(code, "qualifying spend")
+			}
+		}
+	}
+
+	for _, adj := range receipt.Adjustments {
+		codes := []string{}
+		weights := []int64{}
+		for _, code := range basket.Codes() {
+			if qty, ok := adj.Claimed[code]; ok {
+				prod, _ := basket.Product(code)
+				codes = append(codes, code)
+				weights = append(weights, int64(qty)*int64(prod.Price))
+			}
+		}
+		for i, share := range divideAmount(adj.Amount.Amount, weights) {
+			if prod, _ := basket.Product(codes[i]); e.excludes(codes[i], prod) {
+				spend.Amount = c.sub(spend.Amount, share)
+				if c.overflow {
+					return Money{}, basket.overflowError(codes[i], "qualifying spend")
+				}
+			}
+		}
+	}
+
+	return spend, nil
+}
+
+// spendAmountOffRule takes Amount off a basket once its qualifying spend reaches Threshold (e.g. spend 200, get 20 off).
+type spendAmountOffRule struct {
+	SpendExclusions
+	Threshold int
+	Amount    int
+}
+
+// newSpendAmountOffRule is the BasketRuleFactory for the "spendamountoff" basket rule type.
+//
+// Params are the Threshold the qualifying spend must reach, and the Amount taken off, with optional SpendExclusions.
+// A Threshold of 0 applies the rule to any basket (e.g. a voucher).
+func newSpendAmountOffRule(params json.RawMessage) (BasketRule, error) {
+
+	var rule spendAmountOffRule
+	if err := decodeRuleParams(params, &rule); err != nil {
+		return nil, err
+	}
+	if rule.Threshold < 0 {
+		return nil, errors.New("threshold cannot be negative")
+	}
+	if rule.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	return rule, nil
+}
+
+// Adjust takes Amount off the basket if the qualifying spend is at least Threshold,
+// the saving cannot be more than the qualifying spend.
+func (r spendAmountOffRule) Adjust(basket *Basket, receipt Receipt) (Adjustment, error) {
+
+	spend, err := r.QualifyingSpend(basket, receipt)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	if spend.Amount <= 0 || spend.Amount < int64(r.Threshold) {
+		return Adjustment{}, nil
+	}
+
+	saving := basket.money(int64(r.Amount))
+	if saving.Amount > spend.Amount {
+		saving = spend
+	}
+
+	return Adjustment{
+		Rule:         "spendamountoff",
+		Description:  fmt.Sprintf("spend %s, get %s off", basket.money(int64(r.Threshold)), basket.money(int64(r.Amount))),
+		Applications: 1,
+		Claimed:      r.claimed(basket),
+		Amount:       saving.Neg(),
+	}, nil
+}
+
+// spendPercentOffRule takes Percent off the qualifying spend of a basket once it reaches Threshold (e.g. 10% off orders over 500).
+type spendPercentOffRule struct {
+	SpendExclusions
+	Threshold int
+	Percent   int
+}
+
+// newSpendPercentOffRule is the BasketRuleFactory for the "spendpercentoff" basket rule type.
+//
+// Params are the Threshold the qualifying spend must reach, and the Percent taken off, between 1 and 100, with optional SpendExclusions.
+// A Threshold of 0 applies the rule to any basket (e.g. a voucher).
+func newSpendPercentOffRule(params json.RawMessage) (BasketRule, error) {
+
+	var rule spendPercentOffRule
+	if err := decodeRuleParams(params, &rule); err != nil {
+		return nil, err
+	}
+	if rule.Threshold < 0 {
+		return nil, errors.New("threshold cannot be negative")
+	}
+	if rule.Percent <= 0 || rule.Percent > 100 {
+		return nil, errors.New("percent must be between 1 and 100")
+	}
+
+	return rule, nil
+}
+
+// Adjust takes Percent off the qualifying spend if it is at least Threshold, rounded as for percentoff.
+func (r spendPercentOffRule) Adjust(basket *Basket, receipt Receipt) (Adjustment, error) {
+
+	spend, err := r.QualifyingSpend(basket, receipt)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	if spend.Amount <= 0 || spend.Amount < int64(r.Threshold) {
+		return Adjustment{}, nil
+	}
+
+	return Adjustment{
+		Rule:         "spendpercentoff",
+		Description:  fmt.Sprintf("%d%% off orders over %s", r.Percent, basket.money(int64(r.Threshold))),
+		Applications: 1,
+		Claimed:      r.claimed(basket),
+		Amount:       basket.money(-percentOf(spend.Amount, r.Percent)),
+	}, nil
+}
@@ -0,0 +1,195 @@
+package checkout_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_BasketRules tests each of the built in basket rule types through the PriceCheckout function.
+//
+// It passes a slice of CheckoutLine, a map of [productCode]Product and PricingOptions with basket rules to the function,
+// checking for the correct expected checkout price, and whether or not an error is expected.
+func Test_BasketRules(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+		"B": {Price: 35},
+		"C": {Price: 25, Groups: []string{"tobacco"}},
+		"D": {Price: 12, Rules: []checkout.RuleSpec{rule("amountoff", `{"Amount": 2}`)}},
+		"E": {Price: 40, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"E": 1, "C": 1}, "Price": 45}`)}},
+	}
+
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		basketRules   []checkout.RuleSpec
+		expected      int
+		expErr        bool
+	}{
+		{
+			"1: spend threshold reached",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 2}},
+			[]checkout.RuleSpec{rule("spendamountoff", `{"Threshold": 200, "Amount": 20}`)},
+			190,
+			false,
+		},
+		{
+			"2: spend threshold not reached after pricing rules",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 1}},
+			[]checkout.RuleSpec{rule("spendamountoff", `{"Threshold": 200, "Amount": 20}`)},
+			175,
+			false,
+		},
+		{
+			"3: percent off over threshold",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 6}, {Code: "B", Quantity: 7}},
+			[]checkout.RuleSpec{rule("spendpercentoff", `{"Threshold": 500, "Percent": 10}`)},
+			472,
+			false,
+		},
+		{
+			"4: excluded product does not count toward threshold",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "C", Quantity: 3}},
+			[]checkout.RuleSpec{rule("spendamountoff", `{"Threshold": 200, "Amount": 20, "Exclude": ["C"]}`)},
+			215,
+			false,
+		},
+		{
+			"5: excluded group does not count toward threshold, or get discounted",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 6}, {Code: "C", Quantity: 10}},
+			[]checkout.RuleSpec{rule("spendpercentoff", `{"Threshold": 200, "Percent": 10, "ExcludeGroups": ["tobacco"]}`)},
+			502,
+			false,
+		},
+		{
+			"6: excluded product spend is after its pricing rules",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 2}, {Code: "D", Quantity: 5}},
+			[]checkout.RuleSpec{rule("spendpercentoff", `{"Percent": 10, "Exclude": ["D"]}`)},
+			239,
+			false,
+		},
+		{
+			"7: eligible share of a saving claiming excluded and eligible products counts toward threshold",
+			[]checkout.CheckoutLine{{Code: "E", Quantity: 1}, {Code: "C", Quantity: 1}},
+			[]checkout.RuleSpec{rule("spendamountoff", `{"Threshold": 25, "Amount": 5, "Exclude": ["C"]}`)},
+			40,
+			false,
+		},
+		{
+			"8: rules applied in order, each seeing the total after the rules before it",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 2}},
+			[]checkout.RuleSpec{
+				rule("spendamountoff", `{"Threshold": 200, "Amount": 20}`),
+				rule("spendamountoff", `{"Threshold": 200, "Amount": 20}`),
+			},
+			190,
+			false,
+		},
+		{
+			"9: voucher with no threshold, saving limited to spend",
+			[]checkout.CheckoutLine{{Code: "B", Quantity: 1}},
+			[]checkout.RuleSpec{rule("spendamountoff", `{"Amount": 50}`)},
+			0,
+			false,
+		},
+		{
+			"10: unknown basket rule type",
+			[]checkout.CheckoutLine{{Code: "B", Quantity: 1}},
+			[]checkout.RuleSpec{rule("multibuy", `{"Quantity": 2, "Price": 50}`)},
+			0,
+			true,
+		},
+		{
+			"11: basket rule with invalid params",
+			[]checkout.CheckoutLine{{Code: "B", Quantity: 1}},
+			[]checkout.RuleSpec{rule("spendpercentoff", `{"Threshold": 100}`)},
+			0,
+			true,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			receipt, err := checkout.PriceCheckout(testCase.checkoutLines, products, checkout.PricingOptions{BasketRules: testCase.basketRules})
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			// compare result val to expected val
+			if receipt.Total.Amount != int64(testCase.expected) {
+				t.Errorf("expected checkout price of: %v, got checkout price of: %v", testCase.expected, receipt.Total)
+			}
+		})
+	}
+}
+
+// Test_BasketAdjustments checks the adjustments made by basket rules are exposed on the Receipt,
+// separately from the adjustments made by pricing rules.
+func Test_BasketAdjustments(t *testing.T) {
+	receipt, err := checkout.PriceCheckout(
+		[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 2}},
+		map[string]checkout.Product{
+			"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+			"B": {Price: 35},
+		},
+		checkout.PricingOptions{BasketRules: []checkout.RuleSpec{
+			rule("spendamountoff", `{"Threshold": 200, "Amount": 20}`),
+			rule("spendpercentoff", `{"Threshold": 100, "Percent": 10}`),
+		}},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got err: %s", err)
+	}
+
+	expected := []checkout.Adjustment{
+		{Rule: "spendamountoff", Description: "spend £2.00, get £0.20 off", Applications: 1, Claimed: map[string]int{"A": 3, "B": 2}, Amount: gbp(-20)},
+		{Rule: "spendpercentoff", Description: "10% off orders over £1.00", Applications: 1, Claimed: map[string]int{"A": 3, "B": 2}, Amount: gbp(-19)},
+	}
+
+	if !reflect.DeepEqual(receipt.BasketAdjustments, expected) {
+		t.Errorf("expected basket adjustments: %v, got basket adjustments: %v", expected, receipt.BasketAdjustments)
+	}
+	if len(receipt.Adjustments) != 1 || receipt.Adjustments[0].Rule != "multibuy" {
+		t.Errorf("expected a single multibuy adjustment, got adjustments: %v", receipt.Adjustments)
+	}
+	if receipt.Total != gbp(171) {
+		t.Errorf("expected checkout price of: 171, got checkout price of: %v", receipt.Total)
+	}
+}
+
+// Test_RegisterBasketRule tests registering a new basket rule type, and checks registering a duplicate rule type returns an error.
+func Test_RegisterBasketRule(t *testing.T) {
+	factory := func(params json.RawMessage) (checkout.BasketRule, error) {
+		return freeDeliveryRule{}, nil
+	}
+
+	if err := checkout.RegisterBasketRule("freedelivery", factory); err != nil {
+		t.Fatalf("expected no error registering basket rule, got err: %s", err)
+	}
+	if err := checkout.RegisterBasketRule("spendamountoff", factory); err == nil {
+		t.Errorf("expected error registering duplicate basket rule, got nil")
+	}
+
+	receipt, err := checkout.PriceCheckout(
+		[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+		map[string]checkout.Product{"A": {Price: 50}},
+		checkout.PricingOptions{BasketRules: []checkout.RuleSpec{{Type: "freedelivery"}}},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got err: %s", err)
+	}
+	if receipt.Total != gbp(45) {
+		t.Errorf("expected checkout price of: 45, got checkout price of: %v", receipt.Total)
+	}
+}
+
+// freeDeliveryRule is a BasketRule registered by Test_RegisterBasketRule, taking 5 off every basket.
+type freeDeliveryRule struct{}
+
+func (freeDeliveryRule) Adjust(basket *checkout.Basket, receipt checkout.Receipt) (checkout.Adjustment, error) {
+	return checkout.Adjustment{Rule: "freedelivery", Applications: 1, Amount: checkout.Money{Amount: -5, Currency: basket.Currency()}}, nil
+}
@@ -0,0 +1,379 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// CatalogProblem is a problem with a product in a product catalog, found at the JSON Path of one of its fields
+	// (e.g. "Tiers[1].Price"), with Path being empty if the problem is with the product as a whole.
+	// Problems of a product CSV file have no Path, with Err being a *CSVError naming the row and column instead.
+	CatalogProblem struct {
+		Code string
+		Path string
+		Err  error
+	}
+
+	// CatalogError holds every problem found validating a product catalog, in product code order, or row order for a CSV file.
+	//
+	// DecodeProductData, DecodeProductCSV and ValidateProducts return a *CatalogError, errors.Is reports whether any problem matches a target error,
+	// and errors.As finds the first problem matching a target.
+	CatalogError struct {
+		Problems []CatalogProblem
+	}
+
+	// fieldError is an error decoding the field of a product at a JSON path.
+	fieldError struct {
+		Path string
+		Err  error
+	}
+)
+
+// Error returns the problem prefixed with the product code and JSON path (e.g. `product "A": Tiers[1].Price: ...`).
+func (p CatalogProblem) Error() string {
+	if p.Code == "" {
+		return p.Err.Error()
+	}
+	if p.Path == "" {
+		return fmt.Sprintf("product %q: %v", p.Code, p.Err)
+	}
+	return fmt.Sprintf("product %q: %s: %v", p.Code, p.Path, p.Err)
+}
+
+// Unwrap returns the underlying error of the problem.
+func (p CatalogProblem) Unwrap() error {
+	return p.Err
+}
+
+// Error returns every problem in the catalog, separated by semicolons.
+func (e *CatalogError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Error()
+	}
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.Error()
+	}
+	return fmt.Sprintf("%d problems in product catalog: %s", len(e.Problems), strings.Join(problems, "; "))
+}
+
+// Is reports whether any problem in the catalog matches target.
+func (e *CatalogError) Is(target error) bool {
+	for _, problem := range e.Problems {
+		if errors.Is(problem.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first problem in the catalog matching target, as errors.As does, e.g. the *CSVError of a problem in a product CSV file.
+func (e *CatalogError) As(target interface{}) bool {
+	for _, problem := range e.Problems {
+		if errors.As(problem.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *fieldError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateProducts checks every product in a catalog, returning a *CatalogError listing all of the problems found, or nil if there are none.
+//
+// A product is invalid if its OfferQuantity is negative, its tiers, tax class, unit of measure or currency are invalid,
+// one of its Rules cannot be built, or a buyxgety GetCode or bundle Items code is not in the catalog.
+func ValidateProducts(products map[string]Product) error {
+
+	codes := sortedCodes(products)
+	known := map[string]bool{}
+	for _, code := range codes {
+		known[code] = true
+	}
+
+	problems := []CatalogProblem{}
+	for _, code := range codes {
+		problems = append(problems, products[code].problems(code)...)
+		problems = append(problems, products[code].codeProblems(code, known)...)
+	}
+
+	if len(problems) > 0 {
+		return &CatalogError{Problems: problems}
+	}
+	return nil
+}
+
+// sortedCodes returns the product codes of a catalog in order.
+func sortedCodes(products map[string]Product) []string {
+	codes := make([]string, 0, len(products))
+	for code := range products {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// problems returns every problem with a decoded product.
+func (p Product) problems(code string) []CatalogProblem {
+
+	problems := []CatalogProblem{}
+	add := func(path string, err error) {
+		if err != nil {
+			problems = append(problems, CatalogProblem{Code: code, Path: path, Err: err})
+		}
+	}
+
+	if p.OfferQuantity < 0 {
+		add("OfferQuantity", errors.New("offer quantity cannot be negative"))
+	}
+	add("Tiers", p.validateTiers())
+	add("TaxClass", p.validateTaxClass())
+	add("Unit", p.validateUnit())
+	if _, ok := CurrencyExponent(p.currency()); !ok {
+		add("Currency", fmt.Errorf("unsupported currency %q", p.currency()))
+	}
+	for i, spec := range p.Rules {
+		_, err := buildPricingRule(code, spec)
+		// locate unknown parameters within the rule
+		path := fmt.Sprintf("Rules[%d]", i)
+		var fieldErr *fieldError
+		if errors.As(err, &fieldErr) {
+			path, err = joinPath(path, fieldErr.Path), fieldErr.Err
+		}
+		add(path, err)
+	}
+
+	return problems
+}
+
+// codeProblems returns a problem for each product code named by a buyxgety or bundle rule of a product which is not a known code
+// of the catalog, as the rule would never apply. Rules which cannot be built are left to problems.
+func (p Product) codeProblems(code string, known map[string]bool) []CatalogProblem {
+
+	problems := []CatalogProblem{}
+	add := func(path string, ruleCode string) {
+		if !known[ruleCode] {
+			problems = append(problems, CatalogProblem{Code: code, Path: path, Err: fmt.Errorf("unknown product %q", ruleCode)})
+		}
+	}
+
+	for i, spec := range p.Rules {
+		var params struct {
+			GetCode string
+			Items   map[string]int
+		}
+		if json.Unmarshal(spec.Params, &params) != nil {
+			continue
+		}
+		path := fmt.Sprintf("Rules[%d].Params", i)
+		switch spec.Type {
+		case "buyxgety":
+			if params.GetCode != "" {
+				add(joinPath(path, "GetCode"), params.GetCode)
+			}
+		case "bundle":
+			itemCodes := make([]string, 0, len(params.Items))
+			for itemCode := range params.Items {
+				itemCodes = append(itemCodes, itemCode)
+			}
+			sort.Strings(itemCodes)
+			for _, itemCode := range itemCodes {
+				add(joinPath(path, "Items."+itemCode), itemCode)
+			}
+		}
+	}
+
+	return problems
+}
+
+// decodeCatalog decodes and validates a product catalog from JSON, collecting every problem with its products
+// rather than stopping at the first.
+//
+// As well as the problems found by ValidateProducts, a product is invalid if it has a field Product does not,
+// is missing its Price, or has an OfferQuantity with no OfferPrice.
+// An error which is not a *CatalogError is returned if data is not a JSON object.
+func decodeCatalog(data []byte) (map[string]Product, error) {
+
+	rawProducts := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &rawProducts); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, len(rawProducts))
+	for code := range rawProducts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	known := map[string]bool{}
+	for _, code := range codes {
+		known[code] = true
+	}
+
+	products := map[string]Product{}
+	problems := []CatalogProblem{}
+
+	for _, code := range codes {
+		fieldProblems := checkFields(code, rawProducts[code])
+		problems = append(problems, fieldProblems...)
+		if len(fieldProblems) == 1 && fieldProblems[0].Path == "" {
+			continue
+		}
+
+		var prod Product
+		if err := json.Unmarshal(rawProducts[code], &prod); err != nil {
+			problems = append(problems, decodeProblem(code, err))
+			continue
+		}
+		if prod.OfferQuantity != 0 && !hasField(rawProducts[code], "OfferPrice") {
+			problems = append(problems, CatalogProblem{Code: code, Path: "OfferPrice", Err: errors.New("offer price must be given with an offer quantity")})
+		}
+		problems = append(problems, prod.problems(code)...)
+		problems = append(problems, prod.codeProblems(code, known)...)
+		products[code] = prod
+	}
+
+	if len(problems) > 0 {
+		return nil, &CatalogError{Problems: problems}
+	}
+	return products, nil
+}
+
+// checkFields returns a problem for each field of a JSON product, tier or rule which its type does not have,
+// and for a product or tier with no Price.
+func checkFields(code string, raw json.RawMessage) []CatalogProblem {
+
+	problems := []CatalogProblem{}
+	add := func(path string, err error) {
+		problems = append(problems, CatalogProblem{Code: code, Path: path, Err: err})
+	}
+
+	// check the fields of an object against those of a type, returning the object if it is one
+	check := func(path string, raw json.RawMessage, t reflect.Type) map[string]json.RawMessage {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil
+		}
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !knownField(t, name) {
+				add(joinPath(path, name), errors.New("unknown field"))
+			}
+		}
+		if _, ok := lookupField(fields, "Price"); !ok && knownField(t, "Price") {
+			add(joinPath(path, "Price"), errors.New("missing price"))
+		}
+		return fields
+	}
+
+	fields := check("", raw, reflect.TypeOf(Product{}))
+	if fields == nil {
+		return []CatalogProblem{{Code: code, Err: errors.New("product must be a JSON object")}}
+	}
+
+	if tiers, ok := lookupField(fields, "Tiers"); ok {
+		var rawTiers []json.RawMessage
+		if json.Unmarshal(tiers, &rawTiers) == nil {
+			for i, tier := range rawTiers {
+				check(fmt.Sprintf("Tiers[%d]", i), tier, reflect.TypeOf(Tier{}))
+			}
+		}
+	}
+	if rules, ok := lookupField(fields, "Rules"); ok {
+		var rawRules []json.RawMessage
+		if json.Unmarshal(rules, &rawRules) == nil {
+			for i, rule := range rawRules {
+				check(fmt.Sprintf("Rules[%d]", i), rule, reflect.TypeOf(RuleSpec{}))
+			}
+		}
+	}
+
+	return problems
+}
+
+// knownField returns whether or not struct type t has a field matching a JSON key, matched case insensitively as by encoding/json,
+// including the fields of embedded structs.
+func knownField(t reflect.Type, key string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if knownField(field.Type, key) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(field.Name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupField returns the value of a field of a JSON object, matching its key case insensitively.
+func lookupField(fields map[string]json.RawMessage, name string) (json.RawMessage, bool) {
+	for key, value := range fields {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// hasField returns whether or not a JSON object has a non null field with the given name.
+func hasField(raw json.RawMessage, name string) bool {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return false
+	}
+	value, ok := lookupField(fields, name)
+	return ok && string(value) != "null"
+}
+
+// decodeProblem returns the problem of an error decoding a product, at the path of the field which could not be decoded.
+func decodeProblem(code string, err error) CatalogProblem {
+
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		return CatalogProblem{Code: code, Path: fieldErr.Path, Err: fieldErr.Err}
+	}
+
+	// encoding/json gives the path of a field as dot separated names and array indexes (e.g. "Tiers.0.MinQuantity")
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		path := ""
+		for _, part := range strings.Split(typeErr.Field, ".") {
+			if _, err := strconv.Atoi(part); err == nil {
+				path += "[" + part + "]"
+			} else {
+				path = joinPath(path, part)
+			}
+		}
+		return CatalogProblem{Code: code, Path: path, Err: fmt.Errorf("cannot decode JSON %s as %v", typeErr.Value, typeErr.Type)}
+	}
+
+	return CatalogProblem{Code: code, Err: err}
+}
+
+// joinPath appends the name of a field to a JSON path.
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,146 @@
+package checkout_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_DecodeProductDataProblems tests DecodeProductData reports every problem in a product catalog at once,
+// with the product code and JSON path of each.
+func Test_DecodeProductDataProblems(t *testing.T) {
+
+	_, err := checkout.DecodeProductData("../testdata/product_sets/17.json")
+
+	var catalogErr *checkout.CatalogError
+	if !errors.As(err, &catalogErr) {
+		t.Fatalf("expected *CatalogError, got err: %v", err)
+	}
+
+	// compare code and path of each problem to expected vals
+	expected := [][2]string{
+		{"A", "Offer"},
+		{"A", "OfferPrice"},
+		{"B", "Price"},
+		{"B", "OfferQuantity"},
+		{"C", "Tiers[0].MaxQuantity"},
+		{"C", "Tiers[1].Price"},
+		{"C", "Tiers"},
+		{"D", "Price"},
+		{"E", "Rules[0]"},
+		{"F", "TaxClass"},
+		{"F", "Unit"},
+		{"G", "OfferQuantity"},
+		{"H", ""},
+		{"I", "Price"},
+	}
+	problems := [][2]string{}
+	for _, problem := range catalogErr.Problems {
+		problems = append(problems, [2]string{problem.Code, problem.Path})
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected problems: %v, got problems: %v", expected, problems)
+	}
+
+	// check errors.Is finds the overflowing price of product I
+	if !errors.Is(err, checkout.ErrOverflow) {
+		t.Errorf("expected catalog error to match ErrOverflow, got err: %v", err)
+	}
+
+	if s := catalogErr.Problems[1].Error(); s != `product "A": OfferPrice: offer price must be given with an offer quantity` {
+		t.Errorf("unexpected problem string: %s", s)
+	}
+
+	// check a misspelt rule parameter is reported at its path, rather than being ignored
+	_, err = checkout.DecodeProductReader(strings.NewReader(`{"A": {"Price": 50, "Rules": [{"Type": "buyxgety", "Params": {"Buy": 1, "Get": 1, "GetCod": "B"}}]}}`))
+	if s := checkout.FormatError(err); s != `product "A": Rules[0].Params.GetCod: unknown field` {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+
+	// check a rule naming a product code not in the catalog is reported, rather than never applying
+	_, err = checkout.DecodeProductReader(strings.NewReader(`{"A": {"Price": 50, "Rules": [{"Type": "buyxgety", "Params": {"Buy": 1, "Get": 1, "GetCode": "ZZ"}}]}}`))
+	if s := checkout.FormatError(err); s != `product "A": Rules[0].Params.GetCode: unknown product "ZZ"` {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+}
+
+// Test_ValidateProducts tests validating a product catalog built in code, checking each problem is reported.
+func Test_ValidateProducts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		products map[string]checkout.Product
+		expPaths []string
+	}{
+		{
+			"1: valid products",
+			map[string]checkout.Product{
+				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+				"B": {Price: 35, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 10}`)}},
+				"F": {Price: 240, Unit: checkout.UnitKilogram},
+			},
+			nil,
+		},
+		{
+			"2: negative offer quantity",
+			map[string]checkout.Product{"A": {Price: 50, OfferQuantity: -3}},
+			[]string{"OfferQuantity"},
+		},
+		{
+			"3: invalid rule params and unsupported currency",
+			map[string]checkout.Product{"A": {Price: 50, Currency: "XYZ", Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 150}`)}}},
+			[]string{"Currency", "Rules[0]"},
+		},
+		{
+			"4: offer on a measured product",
+			map[string]checkout.Product{"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140, Unit: checkout.UnitKilogram}},
+			[]string{"Unit"},
+		},
+		{
+			"5: unknown rule params",
+			map[string]checkout.Product{"A": {Price: 50, Rules: []checkout.RuleSpec{
+				rule("percentoff", `{"Percent": 10}`),
+				rule("buyxgety", `{"Buy": 1, "Get": 1, "GetCod": "B"}`),
+			}}},
+			[]string{"Rules[1].Params.GetCod"},
+		},
+		{
+			"6: rule codes not in the catalog",
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{
+					rule("buyxgety", `{"Buy": 1, "Get": 1, "GetCode": "ZZ"}`),
+					rule("bundle", `{"Items": {"A": 1, "B": 1, "YY": 2}, "Price": 80}`),
+				}},
+				"B": {Price: 30, Rules: []checkout.RuleSpec{rule("buyxgety", `{"Buy": 1, "Get": 1, "GetCode": "A"}`)}},
+			},
+			[]string{"Rules[0].Params.GetCode", "Rules[1].Params.Items.YY"},
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			err := checkout.ValidateProducts(testCase.products)
+			if testCase.expPaths == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got err: %v", err)
+				}
+				return
+			}
+			var catalogErr *checkout.CatalogError
+			if !errors.As(err, &catalogErr) {
+				t.Fatalf("expected *CatalogError, got err: %v", err)
+			}
+			paths := []string{}
+			for _, problem := range catalogErr.Problems {
+				paths = append(paths, problem.Path)
+			}
+			if !reflect.DeepEqual(paths, testCase.expPaths) {
+				t.Errorf("expected problem paths: %v, got problem paths: %v", testCase.expPaths, paths)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package checkout
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -16,42 +17,91 @@ const (
 	ProductsPath = "./product_data.json"
 )
 
+// Constants StdinPath and StdinName are used when the checkout is read from stdin rather than a file.
+const (
+	// Checkout argument to read the checkout from stdin
+	StdinPath = "-"
+
+	// Name the checkout is reported, and its errors located, with when read from stdin
+	StdinName = "<stdin>"
+)
+
 // ArgInfo is returned from getArgInfo and contains the filepaths for the checkout file/ products file (if they are given)
 //
 // Filepaths may be relative or absolute
 type ArgInfo struct {
-	CheckoutPath string // checkout json file path
-	ProductsPath string // products json file path
+	CheckoutPath    string         // checkout json file path, StdinPath to read the checkout from stdin
+	ProductsPath    string         // products json file path
+	Allocation      AllocationMode // pricing rule allocation mode
+	BasketRulesPath string         // basket rules json file path, "" if not given
+	TaxPath         string         // tax table json file path, "" if not given
+	CollectErrors   bool           // reject checkout lines which cannot be priced, rather than failing the checkout
+	Format          Format         // products file format, "" to choose by its extension
+	Output          OutputFormat   // format the priced checkout is written in
 }
 
 // GetArgInfo returns an instance of ArgInfo.
 //
 // If the checkout info file path has not been given, or the products flag has not been given,
-// checkoutFile/ productsFile will be returned as "" respectively.
+// the default CheckoutPath/ ProductsPath will be returned respectively,
+// unless the checkout file path is not given and os.Stdin is piped or redirected from a file, when StdinPath is returned.
+// If the allocation flag has not been given, AllocationGreedy is returned,
+// and if the basket rules/ tax flags have not been given, BasketRulesPath/ TaxPath are returned as "".
+// CollectErrors is true if the collect-errors flag is given, Format is the format flag value, "" if not given,
+// and Output is the output flag value, OutputText if not given.
 //
 // Filepaths may be relative or absolute.
 func GetArgInfo() ArgInfo {
+	return GetArgInfoReader(os.Stdin)
+}
 
-	var productsPath string
+// GetArgInfoReader returns an instance of ArgInfo as GetArgInfo does, with stdin being the reader the checkout would be read from
+// rather than os.Stdin, or nil if there is none (see stdinPiped).
+func GetArgInfoReader(stdin io.Reader) ArgInfo {
+
+	var productsPath, allocation, basketRulesPath, taxPath, format, output string
+	var collectErrors bool
 
 	commandLine := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 
 	// get products flag value for products file
 	commandLine.StringVar(&productsPath, "products", ProductsPath, "optional filepath to products JSON")
+	// get allocation flag value for how pricing rules are allocated
+	commandLine.StringVar(&allocation, "allocation", string(AllocationGreedy), "optional pricing rule allocation mode, greedy or optimal")
+	// get basket rules flag value for basket rules file
+	commandLine.StringVar(&basketRulesPath, "basket-rules", "", "optional filepath to basket rules JSON")
+	// get tax flag value for tax table file
+	commandLine.StringVar(&taxPath, "tax", "", "optional filepath to tax table JSON")
+	// get collect errors flag value for rejecting invalid checkout lines
+	commandLine.BoolVar(&collectErrors, "collect-errors", false, "optional, price the valid checkout lines and report every invalid line")
+	// get format flag value for the products file format
+	commandLine.StringVar(&format, "format", "", "optional products file format, json, csv, yaml or toml (default by file extension)")
+	// get output flag value for the format the priced checkout is written in
+	commandLine.StringVar(&output, "output", string(OutputText), "optional output format, text, json, csv or table")
 	commandLine.Parse(os.Args[1:])
 
 	// get first positional argument for checkout file
 	checkoutPath := commandLine.Arg(0)
 
-	// set to default CheckoutPath if flag empty/ checkout argument not given
+	// read from stdin if the checkout argument is not given and the checkout is piped in,
+	// otherwise set to default CheckoutPath if flag empty/ checkout argument not given
 
+	if checkoutPath == "" && stdinPiped(stdin) {
+		checkoutPath = StdinPath
+	}
 	if checkoutPath == "" {
 		checkoutPath = CheckoutPath
 	}
 
 	return ArgInfo{
-		CheckoutPath: checkoutPath,
-		ProductsPath: productsPath,
+		CheckoutPath:    checkoutPath,
+		ProductsPath:    productsPath,
+		Allocation:      AllocationMode(allocation),
+		BasketRulesPath: basketRulesPath,
+		TaxPath:         taxPath,
+		CollectErrors:   collectErrors,
+		Format:          Format(format),
+		Output:          OutputFormat(output),
 	}
 }
 
@@ -59,26 +109,118 @@ func GetArgInfo() ArgInfo {
 // It accepts an io.writer to write the result string to.
 //
 // CLI command takes a filename as an argument, expecting a json file of checkout lines,
-// If no filename argument is given, the default checkout dataset will instead be used.
+// If the filename argument is "-", or is not given and stdin is piped (e.g. cat basket.json | checkout-system),
+// the checkout is read from stdin, in a format found from its content (see sniffCheckout in sniff.go),
+// and reported as the StdinName checkout file. Otherwise if no filename argument is given, the default checkout dataset will instead be used.
+// Checkout and products files with a .csv extension are read as CSV (see DecodeCheckoutCSV/ DecodeProductCSV in csv.go),
+// checkout files with a .ndjson or .jsonl extension as a scan log (see DecodeCheckoutNDJSON in ndjson.go),
+// and products files with a .yaml, .yml or .toml extension as YAML or TOML, unless the format flag gives the products format.
 //
-// An optional products flag can also be given to specify a path to a different products list.
+// An optional products flag can also be given to specify a path to a different products list,
+// an optional allocation flag to select how overlapping offers are allocated (greedy or optimal),
+// an optional basket-rules flag to specify a path to basket rules applied to the whole checkout,
+// and an optional tax flag to specify a path to a tax table, with the tax of each tax class written after the total.
+// If the collect-errors flag is given, the valid checkout lines are priced with the rejected lines written after the total,
+// and a *LineErrors holding the error of each rejected line is returned.
+// An optional output flag selects the format the priced checkout is written in, text (the default), json, csv or table (see WriteReceipt).
+//
+// Errors are returned to the caller, which should print them with FormatError to show the file, line and column of any problem.
 func CheckoutCLI(out io.Writer) error {
+	return CheckoutCLIReader(os.Stdin, out)
+}
+
+// CheckoutCLIReader runs the CLI as CheckoutCLI does, reading a checkout piped to stdin from the stdin reader rather than os.Stdin,
+// with a nil stdin meaning there is none.
+func CheckoutCLIReader(stdin io.Reader, out io.Writer) error {
 	// --help info
 	flag.Usage = func() {
-		fmt.Printf("Usage: %s [options] <inJSONLocation>\nOptions:\n", os.Args[0])
+		fmt.Printf("Usage: %s [options] <inJSONLocation | ->\nOptions:\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 
-	argInfo := GetArgInfo()
+	argInfo := GetArgInfoReader(stdin)
+
+	// check the output format before pricing, so nothing is written in an unknown format
+	if err := checkOutputFormat(argInfo.Output); err != nil {
+		return err
+	}
+
+	opts := PricingOptions{Allocation: argInfo.Allocation, CollectErrors: argInfo.CollectErrors}
+
+	// get basket rules if a basket rules file was given
+	if argInfo.BasketRulesPath != "" {
+		basketRules, err := DecodeBasketRules(argInfo.BasketRulesPath)
+		if err != nil {
+			return err
+		}
+		opts.BasketRules = basketRules
+	}
+
+	// get tax table if a tax file was given
+	if argInfo.TaxPath != "" {
+		taxTable, err := DecodeTaxTable(argInfo.TaxPath)
+		if err != nil {
+			return err
+		}
+		opts.Tax = &taxTable
+	}
 
 	// logic to extract from json/ calc checkout value
-	result, err := ProcessCheckout(argInfo.CheckoutPath, argInfo.ProductsPath)
+	checkoutName := argInfo.CheckoutPath
+	var receipt Receipt
+	var err error
+	if checkoutName == StdinPath {
+		checkoutName = StdinName
+		receipt, err = processCheckoutStdin(stdin, argInfo.ProductsPath, argInfo.Format, opts)
+	} else {
+		receipt, err = processCheckoutFiles(argInfo.CheckoutPath, argInfo.ProductsPath, argInfo.Format, opts)
+	}
 
-	if err != nil {
+	// a *LineErrors is returned with the receipt of the valid lines, which is written before the errors are returned
+	var lineErrs *LineErrors
+	if err != nil && !errors.As(err, &lineErrs) {
 		return err
 	}
 
-	fmt.Fprintf(out, "checkout file: %s\nproducts file: %s\ntotal value of checkout: %v\n", argInfo.CheckoutPath, argInfo.ProductsPath, result)
+	if writeErr := WriteReceipt(out, argInfo.Output, checkoutName, argInfo.ProductsPath, receipt); writeErr != nil {
+		return writeErr
+	}
+
+	return err
+}
 
-	return nil
+// processCheckoutStdin prices the checkout read from stdin with the products file at productsPath,
+// locating errors in the checkout as StdinName.
+func processCheckoutStdin(stdin io.Reader, productsPath string, format Format, opts PricingOptions) (Receipt, error) {
+
+	if stdin == nil {
+		return Receipt{}, errors.New("no stdin to read the checkout from")
+	}
+
+	// open products file to read from
+	productsFile, err := os.Open(productsPath)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer productsFile.Close()
+
+	return processCheckout(StdinName, stdin, productsPath, productsFile, format, opts)
+}
+
+// stdinPiped returns true if stdin is a file which is a pipe or a regular file redirected to stdin,
+// rather than a terminal (or another device, such as /dev/null when run by a service or test runner).
+// Any other reader (e.g. a bytes.Buffer) holds a checkout given by the caller, so is treated as piped, and nil is not.
+func stdinPiped(stdin io.Reader) bool {
+	if stdin == nil {
+		return false
+	}
+	file, ok := stdin.(*os.File)
+	if !ok {
+		return true
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
 }
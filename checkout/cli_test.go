@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/billiem/checkout-system/checkout"
@@ -19,19 +20,19 @@ func Test_CheckoutCLI(t *testing.T) {
 		{
 			"1: example data",
 			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
-			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: 284\n",
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
 			false,
 		},
 		{
 			"2: example checkout data with product data with no offers",
 			[]string{"./checkout_system", "-products=../testdata/product_sets/2.json", "../testdata/checkout_sets/1.json"},
-			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/2.json\ntotal value of checkout: 304\n",
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/2.json\ntotal value of checkout: £3.04\n",
 			false,
 		},
 		{
 			"3: checkout data with 0 quantities and example product data",
 			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/4.json"},
-			"checkout file: ../testdata/checkout_sets/4.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: 0\n",
+			"checkout file: ../testdata/checkout_sets/4.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £0.00\n",
 			false,
 		},
 		{
@@ -43,7 +44,7 @@ func Test_CheckoutCLI(t *testing.T) {
 		{
 			"5: example checkout data with product data with negative prices",
 			[]string{"./checkout_system", "-products=../testdata/product_sets/5.json", "../testdata/checkout_sets/1.json"},
-			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/5.json\ntotal value of checkout: -111087\n",
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/5.json\ntotal value of checkout: -£1110.87\n",
 			false,
 		},
 		{
@@ -58,6 +59,106 @@ func Test_CheckoutCLI(t *testing.T) {
 			"",
 			true,
 		},
+		{
+			"8: example data with optimal allocation",
+			[]string{"./checkout_system", "-allocation=optimal", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
+			false,
+		},
+		{
+			"9: example data with basket rules",
+			[]string{"./checkout_system", "-basket-rules=../testdata/basket_rules/1.json", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.64\n",
+			false,
+		},
+		{
+			"10: non-existent basket rules file",
+			[]string{"./checkout_system", "-basket-rules=../testdata/basket_rules/fake.json", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"",
+			true,
+		},
+		{
+			"11: unknown allocation mode",
+			[]string{"./checkout_system", "-allocation=fake", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"",
+			true,
+		},
+		{
+			"12: example data with tax classes and tax table",
+			[]string{"./checkout_system", "-tax=../testdata/tax_tables/1.json", "-products=../testdata/product_sets/13.json", "../testdata/checkout_sets/1.json"},
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/13.json\ntotal value of checkout: £3.17\n" +
+				"standard tax at 20%: £0.28\nreduced tax at 5%: £0.05\nzero tax at 0%: £0.00\n",
+			false,
+		},
+		{
+			"13: invalid tax table",
+			[]string{"./checkout_system", "-tax=../testdata/tax_tables/3.json", "-products=../testdata/product_sets/13.json", "../testdata/checkout_sets/1.json"},
+			"",
+			true,
+		},
+		{
+			"14: collect errors, with rejected lines written after the total of the valid lines",
+			[]string{"./checkout_system", "-collect-errors", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/14.json"},
+			"checkout file: ../testdata/checkout_sets/14.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.00\nrejected checkout lines: 1, 3\n",
+			true,
+		},
+		{
+			"15: collect errors with no invalid lines",
+			[]string{"./checkout_system", "-collect-errors", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
+			false,
+		},
+		{
+			"16: CSV checkout and products files",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/18.csv", "../testdata/checkout_sets/15.csv"},
+			"checkout file: ../testdata/checkout_sets/15.csv\nproducts file: ../testdata/product_sets/18.csv\ntotal value of checkout: £2.84\n",
+			false,
+		},
+		{
+			"17: YAML products file",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/21.yaml", "../testdata/checkout_sets/1.json"},
+			"checkout file: ../testdata/checkout_sets/1.json\nproducts file: ../testdata/product_sets/21.yaml\ntotal value of checkout: £2.84\n",
+			false,
+		},
+		{
+			"18: products format flag overriding the file extension",
+			[]string{"./checkout_system", "-format=json", "-products=../testdata/product_sets/21.yaml", "../testdata/checkout_sets/1.json"},
+			"",
+			true,
+		},
+		{
+			"19: unknown products format",
+			[]string{"./checkout_system", "-format=xml", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"",
+			true,
+		},
+		{
+			"20: NDJSON scan log checkout file",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/17.ndjson"},
+			"checkout file: ../testdata/checkout_sets/17.ndjson\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
+			false,
+		},
+		{
+			"21: csv output",
+			[]string{"./checkout_system", "-output=csv", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"type,code,description,quantity,unit_price,amount\n" +
+				"line,A,,3,0.50,1.50\nline,B,,3,0.35,1.05\nline,C,,1,0.25,0.25\nline,D,,2,0.12,0.24\n" +
+				"promotion,,3 A for £1.40,1,,-0.10\npromotion,,2 B for £0.60,1,,-0.10\n" +
+				"subtotal,,,,,2.84\ntotal,,,,,2.84\n",
+			false,
+		},
+		{
+			"22: unknown output format",
+			[]string{"./checkout_system", "-output=xml", "-products=../testdata/product_sets/1.json", "../testdata/checkout_sets/1.json"},
+			"",
+			true,
+		},
+		{
+			"23: stdin checkout arg with no stdin",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "-"},
+			"",
+			true,
+		},
 	}
 
 	// loop over test cases
@@ -65,10 +166,10 @@ func Test_CheckoutCLI(t *testing.T) {
 		// subtest for each test
 		t.Run(testCase.name, func(t *testing.T) {
 
-			// set args, io.writer & call CheckoutCLI function
+			// set args, io.writer & call CheckoutCLIReader function with no stdin
 			os.Args = testCase.args
 			out := bytes.NewBuffer(nil)
-			err := checkout.CheckoutCLI(out)
+			err := checkout.CheckoutCLIReader(nil, out)
 
 			// check if err expected
 			if (err != nil) != testCase.expErr {
@@ -83,6 +184,81 @@ func Test_CheckoutCLI(t *testing.T) {
 	}
 }
 
+// Test_CheckoutCLIStdin tests checkouts are read from stdin if the checkout argument is "-",
+// or is not given and stdin is redirected from a file, and are reported as <stdin>.
+func Test_CheckoutCLIStdin(t *testing.T) {
+	testCases := []struct {
+		name      string
+		args      []string
+		stdinPath string // file redirected to stdin
+		expected  string
+		expErr    string // expected error formatted with FormatError, "" if no error
+	}{
+		{
+			"1: stdin checkout arg",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "-"},
+			"../testdata/checkout_sets/1.json",
+			"checkout file: <stdin>\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
+			"",
+		},
+		{
+			"2: no checkout arg with redirected stdin",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json"},
+			"../testdata/checkout_sets/1.json",
+			"checkout file: <stdin>\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
+			"",
+		},
+		{
+			"3: CSV checkout from stdin",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "-"},
+			"../testdata/checkout_sets/15.csv",
+			"checkout file: <stdin>\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
+			"",
+		},
+		{
+			"4: gzip compressed checkout from stdin",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "-"},
+			"../testdata/checkout_sets/20.json.gz",
+			"checkout file: <stdin>\nproducts file: ../testdata/product_sets/1.json\ntotal value of checkout: £2.84\n",
+			"",
+		},
+		{
+			"5: error located in stdin",
+			[]string{"./checkout_system", "-products=../testdata/product_sets/1.json", "-"},
+			"../testdata/checkout_sets/18.ndjson",
+			"",
+			"<stdin>:3:3",
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			file, err := os.Open(testCase.stdinPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			// set args, io.writer & call CheckoutCLIReader function with the file as stdin
+			os.Args = testCase.args
+			out := bytes.NewBuffer(nil)
+			err = checkout.CheckoutCLIReader(file, out)
+
+			if testCase.expErr == "" && err != nil {
+				t.Fatalf("expected no error, got err: %s", checkout.FormatError(err))
+			}
+			if testCase.expErr != "" && (err == nil || !strings.HasPrefix(checkout.FormatError(err), testCase.expErr)) {
+				t.Fatalf("expected error at: %s, got err: %v", testCase.expErr, err)
+			}
+			if outStr := out.String(); outStr != testCase.expected {
+				t.Errorf("expected:\n%sgot:\n%s", testCase.expected, outStr)
+			}
+		})
+	}
+}
+
 func Test_GetArgInfo(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -93,40 +269,124 @@ func Test_GetArgInfo(t *testing.T) {
 			"1: no arg/ flag given",
 			[]string{"./checkout_system"},
 			checkout.ArgInfo{
-				"./checkout_data.json",
-				"./product_data.json",
+				CheckoutPath: "./checkout_data.json",
+				ProductsPath: "./product_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				Output:       checkout.OutputText,
 			},
 		},
 		{
 			"2: only checkout arg given",
 			[]string{"./checkout_system", "./other_checkout_data.json"},
 			checkout.ArgInfo{
-				"./other_checkout_data.json",
-				"./product_data.json",
+				CheckoutPath: "./other_checkout_data.json",
+				ProductsPath: "./product_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				Output:       checkout.OutputText,
 			},
 		},
 		{
 			"3: only products flag given",
 			[]string{"./checkout_system", "-products=./other_products_data.json"},
 			checkout.ArgInfo{
-				"./checkout_data.json",
-				"./other_products_data.json",
+				CheckoutPath: "./checkout_data.json",
+				ProductsPath: "./other_products_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				Output:       checkout.OutputText,
 			},
 		},
 		{
 			"4: checkout arg/ products flag both given",
 			[]string{"./checkout_system", "-products=./other_products_data.json", "./other_checkout_data.json"},
 			checkout.ArgInfo{
-				"./other_checkout_data.json",
-				"./other_products_data.json",
+				CheckoutPath: "./other_checkout_data.json",
+				ProductsPath: "./other_products_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				Output:       checkout.OutputText,
 			},
 		},
 		{
 			"5: checkout arg/ products flag both given, + additional positional arg",
 			[]string{"./checkout_system", "-products=./other_products_data.json", "./other_checkout_data.json", "./ignore_products_data.json"},
 			checkout.ArgInfo{
-				"./other_checkout_data.json",
-				"./other_products_data.json",
+				CheckoutPath: "./other_checkout_data.json",
+				ProductsPath: "./other_products_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				Output:       checkout.OutputText,
+			},
+		},
+		{
+			"6: basket rules flag given",
+			[]string{"./checkout_system", "-basket-rules=./basket_rules.json"},
+			checkout.ArgInfo{
+				CheckoutPath:    "./checkout_data.json",
+				ProductsPath:    "./product_data.json",
+				Allocation:      checkout.AllocationGreedy,
+				BasketRulesPath: "./basket_rules.json",
+				Output:          checkout.OutputText,
+			},
+		},
+		{
+			"7: allocation flag given",
+			[]string{"./checkout_system", "-allocation=optimal", "./other_checkout_data.json"},
+			checkout.ArgInfo{
+				CheckoutPath: "./other_checkout_data.json",
+				ProductsPath: "./product_data.json",
+				Allocation:   checkout.AllocationOptimal,
+				Output:       checkout.OutputText,
+			},
+		},
+		{
+			"8: tax flag given",
+			[]string{"./checkout_system", "-tax=./tax_table.json"},
+			checkout.ArgInfo{
+				CheckoutPath: "./checkout_data.json",
+				ProductsPath: "./product_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				TaxPath:      "./tax_table.json",
+				Output:       checkout.OutputText,
+			},
+		},
+		{
+			"9: collect errors flag given",
+			[]string{"./checkout_system", "-collect-errors", "./other_checkout_data.json"},
+			checkout.ArgInfo{
+				CheckoutPath:  "./other_checkout_data.json",
+				ProductsPath:  "./product_data.json",
+				Allocation:    checkout.AllocationGreedy,
+				CollectErrors: true,
+				Output:        checkout.OutputText,
+			},
+		},
+		{
+			"10: format flag given",
+			[]string{"./checkout_system", "-format=toml", "-products=./product_data.toml"},
+			checkout.ArgInfo{
+				CheckoutPath: "./checkout_data.json",
+				ProductsPath: "./product_data.toml",
+				Allocation:   checkout.AllocationGreedy,
+				Format:       checkout.FormatTOML,
+				Output:       checkout.OutputText,
+			},
+		},
+		{
+			"11: stdin checkout arg given",
+			[]string{"./checkout_system", "-products=./other_products_data.json", "-"},
+			checkout.ArgInfo{
+				CheckoutPath: "-",
+				ProductsPath: "./other_products_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				Output:       checkout.OutputText,
+			},
+		},
+		{
+			"12: output flag given",
+			[]string{"./checkout_system", "-output=json", "./other_checkout_data.json"},
+			checkout.ArgInfo{
+				CheckoutPath: "./other_checkout_data.json",
+				ProductsPath: "./product_data.json",
+				Allocation:   checkout.AllocationGreedy,
+				Output:       checkout.OutputJSON,
 			},
 		},
 	}
@@ -135,15 +395,29 @@ func Test_GetArgInfo(t *testing.T) {
 	for _, testCase := range testCases {
 		// run subtest for each testcase
 		t.Run(testCase.name, func(t *testing.T) {
-			// set args and call GetArgInfo
+			// set args and call GetArgInfoReader with no stdin
 			os.Args = testCase.args
 
 			// check expected argInfo matches returned argInfo
-			result := checkout.GetArgInfo()
+			result := checkout.GetArgInfoReader(nil)
 			if !reflect.DeepEqual(result, testCase.expected) {
 				t.Errorf("expected: %v, got %v", testCase.expected, result)
 			}
 		})
 	}
 
+	// check a checkout given as a reader is read from stdin when no checkout argument is given, but not a terminal or device
+	os.Args = []string{"./checkout_system"}
+	if result := checkout.GetArgInfoReader(strings.NewReader(`[{"Code": "A", "Quantity": 1}]`)); result.CheckoutPath != checkout.StdinPath {
+		t.Errorf("expected checkout path: %s, got checkout path: %s", checkout.StdinPath, result.CheckoutPath)
+	}
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	if result := checkout.GetArgInfoReader(devNull); result.CheckoutPath != checkout.CheckoutPath {
+		t.Errorf("expected checkout path: %s, got checkout path: %s", checkout.CheckoutPath, result.CheckoutPath)
+	}
+
 }
@@ -0,0 +1,469 @@
+package checkout
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type (
+	// CSVOptions configures how checkout lines and product catalogs are read from CSV.
+	//
+	// Comma is the field delimiter (e.g. ';' or '\t'), if 0 a comma is used.
+	// The first row of the CSV is a header naming the field of each column, matched case insensitively ignoring spaces,
+	// underscores and hyphens (e.g. "Offer Quantity" is OfferQuantity). Header maps the names of columns to the fields they hold,
+	// for exports using other names (e.g. {"SKU": "Code", "Qty": "Quantity"}), a column mapped to "" is ignored.
+	CSVOptions struct {
+		Comma  rune
+		Header map[string]string
+	}
+
+	// CSVError is returned when a row of a CSV file cannot be read, with Row being its 1-based row number (the header being row 1),
+	// Column the name of the column in the header, if the problem is with one column, and Err the problem.
+	// File is the name of the file the row was read from, if any, and Pos the line and column in the file of the problem,
+	// being the start of the field of Column, or of the row. A row may span more than one line if it has quoted fields.
+	CSVError struct {
+		File   string
+		Row    int
+		Column string
+		Pos    Position
+		Err    error
+	}
+
+	// csvHeader holds the field of each column of a CSV file, the name of the column of each field, and the index of each field's column.
+	csvHeader struct {
+		fields  []string
+		columns map[string]string
+		indexes map[string]int
+	}
+)
+
+var (
+	// checkoutCSVFields are the fields of a checkout line which can be given as CSV columns.
+	checkoutCSVFields = []string{"Code", "Quantity", "Measure"}
+
+	// productCSVFields are the fields of a product which can be given as CSV columns,
+	// Groups and Tiers are lists separated by "|" (e.g. "drinks|snacks", "10:45|50:40"), and Rules a JSON array of RuleSpec.
+	productCSVFields = []string{"Code", "Price", "OfferQuantity", "OfferPrice", "Rules", "Groups", "Tiers", "TierMode", "Currency", "TaxClass", "Unit"}
+)
+
+func (e *CSVError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d, column %q: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeCheckoutCSV takes a filePath and returns a slice of instances of CheckoutLine read from CSV,
+// with a header row and a row for each checkout line (e.g. "code,quantity" followed by "A,3").
+//
+// The Code column is required, with Quantity and Measure being 0 if not given.
+// An error is returned if the file cannot be read, or a *CSVError naming the row and column of the first problem in the file.
+func DecodeCheckoutCSV(filePath string, opts CSVOptions) ([]CheckoutLine, error) {
+
+	// open file to read from
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+	defer file.Close()
+
+	cLSlice, _, err := readCheckoutCSV(filePath, file, opts)
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+
+	return cLSlice, nil
+}
+
+// DecodeCheckoutCSVReader reads checkout CSV from r, as DecodeCheckoutCSV does for a file. A *CSVError has no File.
+func DecodeCheckoutCSVReader(r io.Reader, opts CSVOptions) ([]CheckoutLine, error) {
+
+	cLSlice, _, err := readCheckoutCSV("", r, opts)
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+
+	return cLSlice, nil
+}
+
+// DecodeProductCSV takes a filePath and returns a map of [productCode]Product read from CSV,
+// with a header row and a row for each product (e.g. "code,price,offer quantity,offer price" followed by "A,50,3,140").
+//
+// The Code and Price columns are required. Prices are given as in JSON, as integer minor units or decimal major units (e.g. 50 or 0.50).
+// Groups and Tiers are lists separated by "|" (e.g. "drinks|snacks" and "10:45|50:40" for MinQuantity:Price tiers),
+// and Rules is a JSON array of RuleSpec. Products are validated as by ValidateProducts.
+//
+// An error is returned if the file cannot be read, a *CSVError if its header row is invalid,
+// or a *CatalogError holding a problem for every invalid row, the Err of each being a *CSVError naming its row and column.
+func DecodeProductCSV(filePath string, opts CSVOptions) (map[string]Product, error) {
+
+	// open file to read from
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return map[string]Product{}, err
+	}
+	defer file.Close()
+
+	return readProductsCSV(filePath, file, opts)
+}
+
+// DecodeProductCSVReader reads product CSV from r, as DecodeProductCSV does for a file. A *CSVError has no File.
+func DecodeProductCSVReader(r io.Reader, opts CSVOptions) (map[string]Product, error) {
+	return readProductsCSV("", r, opts)
+}
+
+// newCSVReader returns a csv.Reader reading from r with the delimiter of opts.
+func newCSVReader(r io.Reader, opts CSVOptions) *csv.Reader {
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	// leading spaces are trimmed so quoted fields can follow a space, other than with a whitespace delimiter (e.g. '\t')
+	reader.TrimLeadingSpace = !unicode.IsSpace(reader.Comma)
+	return reader
+}
+
+// readCSVHeader reads the header row of a CSV file, mapping each column to one of fields.
+//
+// An error is returned if a column is not one of fields, a field is given by more than one column, or a required field has no column.
+func readCSVHeader(name string, reader *csv.Reader, opts CSVOptions, fields []string, required ...string) (csvHeader, error) {
+
+	csvErr := func(column string, err error) error {
+		return &CSVError{File: name, Row: 1, Column: column, Err: err}
+	}
+
+	record, err := reader.Read()
+	if err == io.EOF {
+		return csvHeader{}, csvErr("", errors.New("missing header row"))
+	}
+	if err != nil {
+		return csvHeader{}, csvErr("", err)
+	}
+
+	header := csvHeader{fields: make([]string, len(record)), columns: map[string]string{}, indexes: map[string]int{}}
+
+	for i, column := range record {
+		// strip the byte order mark spreadsheet exports may start with
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		column = strings.TrimSpace(column)
+
+		field, mapped := lookupHeader(opts.Header, column)
+		if !mapped {
+			field = column
+		}
+		if field == "" && mapped {
+			continue
+		}
+
+		known := ""
+		for _, f := range fields {
+			if normalizeColumn(f) == normalizeColumn(field) {
+				known = f
+			}
+		}
+		if known == "" {
+			return csvHeader{}, csvErr(column, errors.New("unknown column"))
+		}
+		if _, ok := header.columns[known]; ok {
+			return csvHeader{}, csvErr(column, fmt.Errorf("more than one column for %s", known))
+		}
+		header.fields[i] = known
+		header.columns[known] = column
+		header.indexes[known] = i
+	}
+
+	for _, field := range required {
+		if _, ok := header.columns[field]; !ok {
+			return csvHeader{}, csvErr("", fmt.Errorf("missing %s column", field))
+		}
+	}
+
+	return header, nil
+}
+
+// lookupHeader returns the field a column is mapped to by the Header of CSVOptions, and whether or not it is mapped.
+func lookupHeader(mapping map[string]string, column string) (string, bool) {
+	for name, field := range mapping {
+		if normalizeColumn(name) == normalizeColumn(column) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// normalizeColumn returns the name of a column in lower case, without spaces, underscores or hyphens.
+func normalizeColumn(column string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(column))
+}
+
+// values returns the value of each field of a CSV record, trimmed of spaces.
+func (h csvHeader) values(record []string) map[string]string {
+	values := map[string]string{}
+	for i, value := range record {
+		if i < len(h.fields) && h.fields[i] != "" {
+			values[h.fields[i]] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+// csvError returns a *CSVError for the record last read by reader, at its row of a CSV file named name,
+// with the problem being with the column of field, or with the whole record if field is "".
+func (h csvHeader) csvError(name string, reader *csv.Reader, row int, field string, err error) *CSVError {
+	line, column := reader.FieldPos(h.indexes[field])
+	return &CSVError{File: name, Row: row, Column: h.columns[field], Pos: Position{File: name, Line: line, Column: column}, Err: err}
+}
+
+// csvReadError returns a *CSVError for an error reading a row of a CSV file named name, located at the problem if it is a *csv.ParseError.
+func csvReadError(name string, row int, err error) *CSVError {
+	csvErr := &CSVError{File: name, Row: row, Err: err}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		csvErr.Pos = Position{File: name, Line: parseErr.Line, Column: parseErr.Column}
+	}
+	return csvErr
+}
+
+// readCheckoutCSV reads checkout CSV from r, named name, returning its checkout lines and the Position of each,
+// being the row of the line.
+func readCheckoutCSV(name string, r io.Reader, opts CSVOptions) ([]CheckoutLine, []Position, error) {
+
+	reader := newCSVReader(r, opts)
+	header, err := readCSVHeader(name, reader, opts, checkoutCSVFields, "Code")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cLSlice := []CheckoutLine{}
+	positions := []Position{}
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, csvReadError(name, row, err)
+		}
+
+		values := header.values(record)
+		csvErr := func(field string, err error) error {
+			return header.csvError(name, reader, row, field, err)
+		}
+
+		cL := CheckoutLine{Code: values["Code"]}
+		if quantity := values["Quantity"]; quantity != "" {
+			if cL.Quantity, err = strconv.Atoi(quantity); err != nil {
+				return nil, nil, csvErr("Quantity", fmt.Errorf("invalid quantity %q", quantity))
+			}
+		}
+		if measure := values["Measure"]; measure != "" {
+			m, err := parseDecimal(measure, MeasurePlaces)
+			if err != nil {
+				return nil, nil, csvErr("Measure", fmt.Errorf("measure: %w", err))
+			}
+			cL.Measure = Measure(m)
+		}
+
+		cLSlice = append(cLSlice, cL)
+		line, _ := reader.FieldPos(0)
+		positions = append(positions, Position{File: name, Line: line, Column: 1})
+	}
+
+	return cLSlice, positions, nil
+}
+
+// readProductsCSV reads and validates product CSV from r, named name, collecting the problems of every row into a *CatalogError.
+func readProductsCSV(name string, r io.Reader, opts CSVOptions) (map[string]Product, error) {
+
+	reader := newCSVReader(r, opts)
+	header, err := readCSVHeader(name, reader, opts, productCSVFields, "Code", "Price")
+	if err != nil {
+		return map[string]Product{}, err
+	}
+
+	products := map[string]Product{}
+	problems := []CatalogProblem{}
+
+	// the rules of each row, whose product codes are checked once every row is read
+	type rowRules struct {
+		code     string
+		prod     Product
+		rulesErr *CSVError
+	}
+	rules := []rowRules{}
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return map[string]Product{}, csvReadError(name, row, err)
+			}
+			// the reader moves on to the next row after a malformed row
+			problems = append(problems, CatalogProblem{Err: csvReadError(name, row, err)})
+			continue
+		}
+
+		values := header.values(record)
+		code := values["Code"]
+		problem := func(field string, err error) CatalogProblem {
+			return CatalogProblem{Code: code, Err: header.csvError(name, reader, row, field, err)}
+		}
+
+		if code == "" {
+			problems = append(problems, problem("Code", errors.New("missing product code")))
+			continue
+		}
+		if _, ok := products[code]; ok {
+			problems = append(problems, problem("Code", fmt.Errorf("duplicate product code %q", code)))
+			continue
+		}
+
+		prod, field, err := csvProduct(values)
+		if err != nil {
+			problems = append(problems, problem(field, err))
+		} else {
+			// validate the product as for JSON, naming the column of each problem
+			for _, p := range prod.problems(code) {
+				problems = append(problems, problem(strings.SplitN(p.Path, "[", 2)[0], p.Err))
+			}
+			if len(prod.Rules) > 0 {
+				rules = append(rules, rowRules{code: code, prod: prod, rulesErr: header.csvError(name, reader, row, "Rules", nil)})
+			}
+		}
+
+		products[code] = prod
+	}
+
+	known := map[string]bool{}
+	for code := range products {
+		known[code] = true
+	}
+	for _, r := range rules {
+		for _, p := range r.prod.codeProblems(r.code, known) {
+			csvErr := *r.rulesErr
+			csvErr.Err = p.Err
+			problems = append(problems, CatalogProblem{Code: r.code, Err: &csvErr})
+		}
+	}
+	// keep the problems in row order
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problemRow(problems[i]) < problemRow(problems[j])
+	})
+
+	if len(problems) > 0 {
+		return map[string]Product{}, &CatalogError{Problems: problems}
+	}
+	return products, nil
+}
+
+// problemRow returns the row of the *CSVError of a problem in a product CSV file.
+func problemRow(problem CatalogProblem) int {
+	var csvErr *CSVError
+	if errors.As(problem.Err, &csvErr) {
+		return csvErr.Row
+	}
+	return 0
+}
+
+// csvProduct decodes a Product from the values of a CSV row, returning the field of the column which could not be decoded with any error.
+func csvProduct(values map[string]string) (Product, string, error) {
+
+	prod := Product{
+		Currency: values["Currency"],
+		TaxClass: values["TaxClass"],
+		Unit:     values["Unit"],
+		TierMode: values["TierMode"],
+	}
+	var err error
+
+	if values["Price"] == "" {
+		return Product{}, "Price", errors.New("missing price")
+	}
+	if prod.Price, err = csvPrice(values["Price"], prod.currency()); err != nil {
+		return Product{}, "Price", err
+	}
+	if offerQuantity := values["OfferQuantity"]; offerQuantity != "" {
+		if prod.OfferQuantity, err = strconv.Atoi(offerQuantity); err != nil {
+			return Product{}, "OfferQuantity", fmt.Errorf("invalid offer quantity %q", offerQuantity)
+		}
+	}
+	if prod.OfferQuantity != 0 && values["OfferPrice"] == "" {
+		return Product{}, "OfferPrice", errors.New("offer price must be given with an offer quantity")
+	}
+	if values["OfferPrice"] != "" {
+		if prod.OfferPrice, err = csvPrice(values["OfferPrice"], prod.currency()); err != nil {
+			return Product{}, "OfferPrice", err
+		}
+	}
+
+	prod.Groups = csvList(values["Groups"])
+	for _, tier := range csvList(values["Tiers"]) {
+		parts := strings.SplitN(tier, ":", 2)
+		if len(parts) != 2 {
+			return Product{}, "Tiers", fmt.Errorf("tier %q must be given as MinQuantity:Price", tier)
+		}
+		minQuantity, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return Product{}, "Tiers", fmt.Errorf("invalid tier minimum quantity %q", parts[0])
+		}
+		price, err := csvPrice(strings.TrimSpace(parts[1]), prod.currency())
+		if err != nil {
+			return Product{}, "Tiers", err
+		}
+		prod.Tiers = append(prod.Tiers, Tier{MinQuantity: minQuantity, Price: price})
+	}
+	if rules := values["Rules"]; rules != "" {
+		if err := json.Unmarshal([]byte(rules), &prod.Rules); err != nil {
+			return Product{}, "Rules", fmt.Errorf("rules must be a JSON array of rules: %w", err)
+		}
+	}
+
+	return prod, "", nil
+}
+
+// csvPrice parses a price from CSV as it is from JSON, integers being minor units and decimals being major units.
+func csvPrice(price string, currencyCode string) (int, error) {
+
+	// integers are already minor units
+	if digits := strings.TrimPrefix(price, "-"); digits != "" && strings.Trim(digits, "0123456789") == "" {
+		minor, err := strconv.ParseInt(price, 10, 0)
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("%w: %s is too large", ErrOverflow, price)
+		}
+		return int(minor), err
+	}
+
+	return decodePrice(json.RawMessage(strconv.Quote(price)), currencyCode)
+}
+
+// csvList splits a CSV value into the items of a list separated by "|", leaving out empty items.
+func csvList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
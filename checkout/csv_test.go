@@ -0,0 +1,285 @@
+package checkout_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_DecodeCheckoutCSV tests reading checkout lines from CSV, with header mapping and row numbered errors.
+func Test_DecodeCheckoutCSV(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		opts     checkout.CSVOptions
+		expected []checkout.CheckoutLine
+		expErr   string
+	}{
+		{
+			"1: code and quantity",
+			"code,quantity\nA,3\nB, 1\n",
+			checkout.CSVOptions{},
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 1}},
+			"",
+		},
+		{
+			"2: mapped header and delimiter, with a measure",
+			"SKU;Qty;Weight\nA;3;\nF;;1.25\n",
+			checkout.CSVOptions{Comma: ';', Header: map[string]string{"SKU": "Code", "Qty": "Quantity", "Weight": "Measure"}},
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "F", Measure: 1250}},
+			"",
+		},
+		{
+			"3: ignored column",
+			"code,description,quantity\nA,apple,3\n",
+			checkout.CSVOptions{Header: map[string]string{"description": ""}},
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}},
+			"",
+		},
+		{
+			"4: invalid quantity",
+			"code,quantity\nA,3\nB,two\n",
+			checkout.CSVOptions{},
+			nil,
+			`row 3, column "quantity": invalid quantity "two"`,
+		},
+		{
+			"5: unknown column",
+			"code,qty\nA,3\n",
+			checkout.CSVOptions{},
+			nil,
+			`row 1, column "qty": unknown column`,
+		},
+		{
+			"6: missing code column",
+			"quantity\n3\n",
+			checkout.CSVOptions{},
+			nil,
+			"row 1: missing Code column",
+		},
+		{
+			"7: wrong number of fields",
+			"code,quantity\nA,3,1\n",
+			checkout.CSVOptions{},
+			nil,
+			"row 2: record on line 2: wrong number of fields",
+		},
+		{
+			"8: no header row",
+			"",
+			checkout.CSVOptions{},
+			nil,
+			"row 1: missing header row",
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeCheckoutCSVReader(strings.NewReader(testCase.data), testCase.opts)
+			// check error message matches expected
+			if testCase.expErr != "" {
+				var csvErr *checkout.CSVError
+				if !errors.As(err, &csvErr) || err.Error() != testCase.expErr {
+					t.Errorf("expected *CSVError: %s, got err: %v", testCase.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got err: %v", err)
+			}
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected: %v, got %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+// Test_DecodeProductCSV tests reading product catalogs from CSV, including promotion columns,
+// and that products are validated with a *CSVError for the problem of each invalid row.
+func Test_DecodeProductCSV(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		opts     checkout.CSVOptions
+		expected map[string]checkout.Product
+		expErr   string
+	}{
+		{
+			"1: example products with offers",
+			"Code,Price,Offer Quantity,Offer Price\nA,50,3,140\nB,0.35,2,0.60\nC,25,,\n",
+			checkout.CSVOptions{},
+			map[string]checkout.Product{
+				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+				"B": {Price: 35, OfferQuantity: 2, OfferPrice: 60},
+				"C": {Price: 25},
+			},
+			"",
+		},
+		{
+			"2: promotion columns",
+			"code\tprice\tgroups\ttiers\trules\tcurrency\nA\t0.50\tfruit|snacks\t10:45|50:0.40\t\tGBP\nB\t35\t\t\t\"[{\"\"Type\"\": \"\"percentoff\"\", \"\"Params\"\": {\"\"Percent\"\": 10}}]\"\t\n",
+			checkout.CSVOptions{Comma: '\t'},
+			map[string]checkout.Product{
+				"A": {Price: 50, Groups: []string{"fruit", "snacks"}, Tiers: []checkout.Tier{{MinQuantity: 10, Price: 45}, {MinQuantity: 50, Price: 40}}, Currency: "GBP"},
+				"B": {Price: 35, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 10}`)}},
+			},
+			"",
+		},
+		{
+			"3: missing price",
+			"code,price\nA,50\nB,\n",
+			checkout.CSVOptions{},
+			nil,
+			`product "B": row 3, column "price": missing price`,
+		},
+		{
+			"4: offer quantity with no offer price",
+			"code,price,offer_quantity\nA,50,3\n",
+			checkout.CSVOptions{},
+			nil,
+			`product "A": row 2: offer price must be given with an offer quantity`,
+		},
+		{
+			"5: invalid product, named by its column",
+			"code,price,tax class\nA,50,luxury\n",
+			checkout.CSVOptions{},
+			nil,
+			`product "A": row 2, column "tax class": unknown tax class "luxury"`,
+		},
+		{
+			"6: duplicate product code",
+			"code,price\nA,50\nA,40\n",
+			checkout.CSVOptions{},
+			nil,
+			`product "A": row 3, column "code": duplicate product code "A"`,
+		},
+		{
+			"7: too many decimal places",
+			"code,price\nA,0.505\n",
+			checkout.CSVOptions{},
+			nil,
+			`product "A": row 2, column "price": GBP amount: "0.505" has more than 2 decimal places`,
+		},
+		{
+			"8: every invalid row",
+			"code,price,tax class\nA,50,\nB,,\n,40,\nC,\"4\"0,\nD,0.505,\nE,30,luxury\n",
+			checkout.CSVOptions{},
+			nil,
+			`5 problems in product catalog: product "B": row 3, column "price": missing price; row 4, column "code": missing product code; ` +
+				`row 5: parse error on line 5, column 5: extraneous or missing " in quoted-field; ` +
+				`product "D": row 6, column "price": GBP amount: "0.505" has more than 2 decimal places; ` +
+				`product "E": row 7, column "tax class": unknown tax class "luxury"`,
+		},
+		{
+			"9: rule code not in the catalog, in row order with later rows",
+			"code,price,rules\n" +
+				`A,50,"[{""Type"": ""buyxgety"", ""Params"": {""Buy"": 1, ""Get"": 1, ""GetCode"": ""ZZ""}}]"` + "\n" +
+				"B,,\n" +
+				`C,30,"[{""Type"": ""buyxgety"", ""Params"": {""Buy"": 1, ""Get"": 1, ""GetCode"": ""A""}}]"` + "\n",
+			checkout.CSVOptions{},
+			nil,
+			`2 problems in product catalog: product "A": row 2, column "rules": unknown product "ZZ"; product "B": row 3, column "price": missing price`,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeProductCSVReader(strings.NewReader(testCase.data), testCase.opts)
+			// check error message matches expected, with each problem being a *CSVError
+			if testCase.expErr != "" {
+				var catalogErr *checkout.CatalogError
+				if !errors.As(err, &catalogErr) || err.Error() != testCase.expErr {
+					t.Fatalf("expected *CatalogError: %s, got err: %v", testCase.expErr, err)
+				}
+				for _, problem := range catalogErr.Problems {
+					var csvErr *checkout.CSVError
+					if !errors.As(problem.Err, &csvErr) {
+						t.Errorf("expected *CSVError, got err: %v", problem.Err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got err: %v", err)
+			}
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected: %+v, got %+v", testCase.expected, result)
+			}
+		})
+	}
+	// check problems are located at the line and column of their field, with a quoted field spanning lines
+	_, err := checkout.DecodeProductCSVReader(strings.NewReader("code,price,rules\nA,50,\"[\n]\"\nB,0.505,\n"), checkout.CSVOptions{})
+	var csvErr *checkout.CSVError
+	if !errors.As(err, &csvErr) {
+		t.Fatalf("expected *CSVError, got err: %v", err)
+	}
+	if csvErr.Row != 3 || csvErr.Pos.Line != 4 || csvErr.Pos.Column != 3 {
+		t.Errorf("expected row 3 at line 4, column 3, got row %d at: %+v", csvErr.Row, csvErr.Pos)
+	}
+}
+
+// Test_ProcessCheckoutCSV tests CSV checkout and product files are selected by their extension,
+// and that errors are formatted with the file and row.
+func Test_ProcessCheckoutCSV(t *testing.T) {
+	testCases := []struct {
+		name         string
+		checkoutPath string
+		productsPath string
+		expTotal     int
+		expErr       string
+	}{
+		{"1: CSV checkout and products", "../testdata/checkout_sets/15.csv", "../testdata/product_sets/18.csv", 284, ""},
+		{"2: CSV checkout with JSON products", "../testdata/checkout_sets/15.csv", "../testdata/product_sets/1.json", 284, ""},
+		{"3: JSON checkout with CSV products", "../testdata/checkout_sets/1.json", "../testdata/product_sets/18.csv", 284, ""},
+		{
+			"4: invalid checkout row",
+			"../testdata/checkout_sets/16.csv",
+			"../testdata/product_sets/18.csv",
+			0,
+			`../testdata/checkout_sets/16.csv:3:3: row 3, column "quantity": invalid quantity "two"`,
+		},
+		{
+			"5: invalid product row",
+			"../testdata/checkout_sets/15.csv",
+			"../testdata/product_sets/20.csv",
+			0,
+			`../testdata/product_sets/20.csv:2:1: product "A": row 2: offer price must be given with an offer quantity`,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			receipt, err := checkout.ProcessCheckoutReceipt(testCase.checkoutPath, testCase.productsPath, checkout.PricingOptions{})
+			if testCase.expErr != "" {
+				if s := checkout.FormatError(err); s != testCase.expErr {
+					t.Errorf("expected formatted error: %s, got formatted error: %s", testCase.expErr, s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got err: %v", err)
+			}
+			if receipt.Total != gbp(int64(testCase.expTotal)) {
+				t.Errorf("expected checkout price of: %d, got checkout price of: %v", testCase.expTotal, receipt.Total)
+			}
+		})
+	}
+
+	// check a mapped header and delimiter are used by DecodeProductCSV
+	products, err := checkout.DecodeProductCSV("../testdata/product_sets/19.csv", checkout.CSVOptions{Comma: ';', Header: map[string]string{"SKU": "Code", "Description": ""}})
+	if err != nil {
+		t.Fatalf("expected no error, got err: %v", err)
+	}
+	if len(products) != 3 || products["A"].TaxClass != checkout.TaxClassReduced || len(products["B"].Rules) != 1 || len(products["C"].Groups) != 2 {
+		t.Errorf("unexpected products: %+v", products)
+	}
+}
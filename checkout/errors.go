@@ -0,0 +1,329 @@
+package checkout
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownProduct is returned (wrapped) when a checkout line has no product code, or a product code not in the products map.
+	ErrUnknownProduct = errors.New("no product code or product code not found in products map")
+
+	// ErrNegativeQuantity is returned (wrapped) when a checkout line has a negative quantity or measure.
+	ErrNegativeQuantity = errors.New("checkout line quantity cannot be negative")
+)
+
+type (
+	// Position is a location in a JSON file, with Offset being the byte offset from the start of the file,
+	// and Line and Column the 1-based line and byte column of that offset. A zero Line means the location is not known.
+	Position struct {
+		File   string
+		Offset int64
+		Line   int
+		Column int
+	}
+
+	// LineError is returned when a checkout line cannot be priced (e.g. its product is unknown, or its quantity is negative).
+	//
+	// Index is the index of the line in the checkout, Code its product code, and Err the problem with the line,
+	// which wraps ErrUnknownProduct, ErrNegativeQuantity or ErrCurrencyMismatch where one applies.
+	// Pos is the location of the line in its checkout file, if it was decoded from one by ProcessCheckoutReceipt.
+	LineError struct {
+		Pos   Position
+		Index int
+		Code  string
+		Err   error
+	}
+
+	// LineErrors is returned when pricing a checkout with the CollectErrors option of PricingOptions,
+	// holding the error of each checkout line which was rejected, in checkout line order.
+	// Each error is a *LineError or an *OverflowError naming the line, errors.Is reports whether any matches a target error.
+	LineErrors struct {
+		Errors []error
+	}
+
+	// SyntaxError is returned when a JSON file is malformed, or a value in it has the wrong type,
+	// with Pos being the location of the problem and Err the error from encoding/json.
+	SyntaxError struct {
+		Pos Position
+		Err error
+	}
+)
+
+// String formats the position as "file:line:column", leaving out the parts which are not known.
+func (p Position) String() string {
+	s := p.File
+	if p.Line > 0 {
+		if s != "" {
+			s += ":"
+		}
+		s += strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	}
+	return s
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("checkout line %d (product %q): %v", e.Index, e.Code, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Error returns the error of every rejected line, separated by semicolons.
+func (e *LineErrors) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d checkout lines rejected: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+// Is reports whether the error of any rejected line matches target.
+func (e *LineErrors) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lines returns the index of each rejected checkout line.
+func (e *LineErrors) Lines() []int {
+	lines := make([]int, len(e.Errors))
+	for i, err := range e.Errors {
+		lines[i] = lineIndex(err)
+	}
+	return lines
+}
+
+// lineIndex returns the index of the checkout line named by a *LineError or *OverflowError, or -1 if err names no line.
+func lineIndex(err error) int {
+	var lineErr *LineError
+	var overflowErr *OverflowError
+	switch {
+	case errors.As(err, &lineErr):
+		return lineErr.Index
+	case errors.As(err, &overflowErr):
+		return overflowErr.Line
+	}
+	return -1
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// FormatError formats an error in the form "file:line:column: message" if it is a *SyntaxError, *LineError or *OverflowError
+// with a known Position, or "file:line:column: row N: message" for a *CSVError, otherwise the error message is returned as it is.
+// The errors of a *LineErrors, and the problems of a *CatalogError read from a product CSV file, are each formatted on their own line.
+func FormatError(err error) string {
+
+	var lineErrs *LineErrors
+	if errors.As(err, &lineErrs) {
+		messages := make([]string, len(lineErrs.Errors))
+		for i, err := range lineErrs.Errors {
+			messages[i] = FormatError(err)
+		}
+		return strings.Join(messages, "\n")
+	}
+
+	// each problem of a product CSV file is located at its row
+	var catalogErr *CatalogError
+	if errors.As(err, &catalogErr) && len(catalogErr.Problems) > 0 {
+		var csvErr *CSVError
+		if errors.As(catalogErr.Problems[0].Err, &csvErr) {
+			messages := make([]string, len(catalogErr.Problems))
+			for i, problem := range catalogErr.Problems {
+				messages[i] = FormatError(problem)
+			}
+			return strings.Join(messages, "\n")
+		}
+	}
+
+	// rows of CSV files are named by their row number, as well as the line and column of the row in the file
+	var csvErr *CSVError
+	if errors.As(err, &csvErr) && csvErr.File != "" {
+		return csvErr.Pos.String() + ": " + err.Error()
+	}
+
+	var syntaxErr *SyntaxError
+	var lineErr *LineError
+	var overflowErr *OverflowError
+
+	var pos Position
+	switch {
+	case errors.As(err, &syntaxErr):
+		pos = syntaxErr.Pos
+	case errors.As(err, &lineErr):
+		pos = lineErr.Pos
+	case errors.As(err, &overflowErr):
+		pos = overflowErr.Pos
+	}
+
+	if prefix := pos.String(); prefix != "" {
+		return prefix + ": " + err.Error()
+	}
+	return err.Error()
+}
+
+// position returns the Position of a byte offset in data, read from file.
+func position(file string, data []byte, offset int64) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	return Position{
+		File:   file,
+		Offset: offset,
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: len(before) - bytes.LastIndexByte(before, '\n'),
+	}
+}
+
+// syntaxError returns an error decoding JSON from data as a *SyntaxError, located at the offset given by encoding/json for syntax errors.
+//
+// base is the offset in data of the JSON value err was returned decoding, and start the offset used for other errors.
+func syntaxError(file string, data []byte, base int64, start int64, err error) *SyntaxError {
+	at := func(offset int64) Position {
+		return position(file, data, offset)
+	}
+	return jsonSyntaxError(at, int64(len(data)), base, start, err)
+}
+
+// jsonSyntaxError is syntaxError for JSON which may not be held in memory, with at returning the Position of an offset,
+// and end being the offset of the end of the input.
+func jsonSyntaxError(at func(offset int64) Position, end int64, base int64, start int64, err error) *SyntaxError {
+
+	var jsonSyntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &jsonSyntaxErr):
+		// the offset of a syntax error is just after the character which could not be read
+		return &SyntaxError{Pos: at(base + jsonSyntaxErr.Offset - 1), Err: err}
+	case errors.As(err, &typeErr):
+		return &SyntaxError{Pos: at(start), Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF):
+		return &SyntaxError{Pos: at(end), Err: errors.New("unexpected end of JSON input")}
+	}
+	return &SyntaxError{Pos: at(start), Err: err}
+}
+
+// decodeCheckout decodes checkout lines from a JSON array in data, read from file, also returning the Position of each line.
+//
+// An error decoding the data is returned as a *SyntaxError, located at the problem if encoding/json gives its offset,
+// otherwise at the start of the line which could not be decoded.
+func decodeCheckout(file string, data []byte) ([]CheckoutLine, []Position, error) {
+
+	// locate lines by counting the newlines read, rather than those before each line
+	counter := &lineCounter{r: bytes.NewReader(data), first: -1, lastNewline: -1}
+	dec := json.NewDecoder(counter)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, syntaxError(file, data, 0, 0, err)
+	}
+	if tok != json.Delim('[') {
+		start := skipSeparators(data, 0)
+		return nil, nil, &SyntaxError{Pos: position(file, data, start), Err: errors.New("checkout data must be a JSON array of checkout lines")}
+	}
+
+	cLSlice := []CheckoutLine{}
+	positions := []Position{}
+
+	for dec.More() {
+		start := skipSeparators(data, dec.InputOffset())
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, syntaxError(file, data, 0, start, err)
+		}
+
+		var cL CheckoutLine
+		if err := json.Unmarshal(raw, &cL); err != nil {
+			return nil, nil, syntaxError(file, data, start, start, err)
+		}
+
+		cLSlice = append(cLSlice, cL)
+		positions = append(positions, counter.position(file, start))
+	}
+
+	// read the closing bracket, and check nothing follows it
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, syntaxError(file, data, 0, dec.InputOffset(), err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		end := skipSeparators(data, dec.InputOffset())
+		return nil, nil, &SyntaxError{Pos: position(file, data, end), Err: errors.New("invalid data after checkout lines")}
+	}
+
+	return cLSlice, positions, nil
+}
+
+// skipSeparators returns the offset of the first byte in data from offset which is not JSON whitespace or a comma.
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\n', '\r', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// locate sets the Position of a *LineError or *OverflowError returned pricing a checkout, or each of the errors of a *LineErrors,
+// from the positions of its lines.
+func locate(err error, positions []Position) error {
+
+	var lineErrs *LineErrors
+	var lineErr *LineError
+	var overflowErr *OverflowError
+
+	switch {
+	case errors.As(err, &lineErrs):
+		for _, err := range lineErrs.Errors {
+			locate(err, positions)
+		}
+	case errors.As(err, &lineErr) && lineErr.Index >= 0 && lineErr.Index < len(positions):
+		lineErr.Pos = positions[lineErr.Index]
+	case errors.As(err, &overflowErr) && overflowErr.Line >= 0 && overflowErr.Line < len(positions):
+		overflowErr.Pos = positions[overflowErr.Line]
+	}
+
+	return err
+}
+
+// locateAt sets the Position of a *LineError or *OverflowError to pos.
+func locateAt(err error, pos Position) error {
+
+	var lineErr *LineError
+	var overflowErr *OverflowError
+
+	switch {
+	case errors.As(err, &lineErr):
+		lineErr.Pos = pos
+	case errors.As(err, &overflowErr):
+		overflowErr.Pos = pos
+	}
+
+	return err
+}
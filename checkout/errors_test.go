@@ -0,0 +1,124 @@
+package checkout_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_DecodeCheckoutDataSyntaxError tests malformed checkout files return a *SyntaxError, located at the problem in the file.
+func Test_DecodeCheckoutDataSyntaxError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		filePath  string
+		expPos    string
+		expFormat string
+	}{
+		{
+			"1: missing comma between lines",
+			"../testdata/checkout_sets/12.json",
+			"../testdata/checkout_sets/12.json:3:5",
+			"../testdata/checkout_sets/12.json:3:5: invalid character '{' after array element",
+		},
+		{
+			"2: object rather than array",
+			"../testdata/checkout_sets/8.json",
+			"../testdata/checkout_sets/8.json:1:1",
+			"../testdata/checkout_sets/8.json:1:1: checkout data must be a JSON array of checkout lines",
+		},
+		{
+			"3: blank file",
+			"../testdata/checkout_sets/0.txt",
+			"../testdata/checkout_sets/0.txt:1:1",
+			"../testdata/checkout_sets/0.txt:1:1: unexpected end of JSON input",
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := checkout.DecodeCheckoutData(testCase.filePath)
+			var syntaxErr *checkout.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected *SyntaxError, got err: %v", err)
+			}
+			if pos := syntaxErr.Pos.String(); pos != testCase.expPos {
+				t.Errorf("expected position: %s, got position: %s", testCase.expPos, pos)
+			}
+			if s := checkout.FormatError(err); s != testCase.expFormat {
+				t.Errorf("expected formatted error: %s, got formatted error: %s", testCase.expFormat, s)
+			}
+		})
+	}
+
+	// check a value of the wrong type is located at the start of its line, and wraps the encoding/json error
+	_, err := checkout.DecodeCheckoutData("../testdata/checkout_sets/13.json")
+	var syntaxErr *checkout.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &syntaxErr) || !errors.As(err, &typeErr) {
+		t.Fatalf("expected *SyntaxError wrapping *json.UnmarshalTypeError, got err: %v", err)
+	}
+	if syntaxErr.Pos.Line != 3 || syntaxErr.Pos.Column != 5 {
+		t.Errorf("expected position: 3:5, got position: %d:%d", syntaxErr.Pos.Line, syntaxErr.Pos.Column)
+	}
+}
+
+// Test_ProcessCheckoutLineError tests checkout lines which cannot be priced return a *LineError
+// with the index, product code and position of the line in the checkout file.
+func Test_ProcessCheckoutLineError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		filePath  string
+		expIndex  int
+		expCode   string
+		expErr    error
+		expFormat string
+	}{
+		{
+			"1: unknown product",
+			"../testdata/checkout_sets/10.json",
+			1,
+			"E",
+			checkout.ErrUnknownProduct,
+			`../testdata/checkout_sets/10.json:6:5: checkout line 1 (product "E"): no product code or product code not found in products map`,
+		},
+		{
+			"2: negative quantity",
+			"../testdata/checkout_sets/11.json",
+			1,
+			"B",
+			checkout.ErrNegativeQuantity,
+			`../testdata/checkout_sets/11.json:6:5: checkout line 1 (product "B"): checkout line quantity cannot be negative`,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := checkout.ProcessCheckoutReceipt(testCase.filePath, "../testdata/product_sets/1.json", checkout.PricingOptions{})
+			var lineErr *checkout.LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("expected *LineError, got err: %v", err)
+			}
+			if lineErr.Index != testCase.expIndex || lineErr.Code != testCase.expCode {
+				t.Errorf("expected line: %d (%s), got line: %d (%s)", testCase.expIndex, testCase.expCode, lineErr.Index, lineErr.Code)
+			}
+			if !errors.Is(err, testCase.expErr) {
+				t.Errorf("expected error to wrap: %v, got err: %v", testCase.expErr, err)
+			}
+			if s := checkout.FormatError(err); s != testCase.expFormat {
+				t.Errorf("expected formatted error: %s, got formatted error: %s", testCase.expFormat, s)
+			}
+		})
+	}
+
+	// check errors pricing checkout lines in code have no position
+	_, err := checkout.GetCheckoutPrice([]checkout.CheckoutLine{{Code: "E", Quantity: 1}}, map[string]checkout.Product{})
+	if s := checkout.FormatError(err); s != `checkout line 0 (product "E"): no product code or product code not found in products map` {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+}
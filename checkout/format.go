@@ -0,0 +1,261 @@
+package checkout
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of a checkout or product catalog file, chosen by its extension if not given (see FormatOf).
+type Format string
+
+// File formats, checkouts may be JSON, CSV or NDJSON, and product catalogs JSON, CSV, YAML or TOML.
+// YAML and TOML catalogs have the same structure as JSON catalogs, and may have comments.
+const (
+	FormatJSON   Format = "json"
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatYAML   Format = "yaml"
+	FormatTOML   Format = "toml"
+)
+
+// FormatOf returns the Format of a file by its extension, .csv, .ndjson/ .jsonl, .yaml/ .yml and .toml files being CSV, NDJSON,
+// YAML and TOML, and any other file being JSON.
+func FormatOf(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// DecodeProductDataFormat takes a filePath and returns a map of [productCode]Product decoded from a catalog in the given Format,
+// or in the Format of its extension if format is "".
+//
+// Catalogs are validated as by DecodeProductData, with a *CatalogError listing every problem found.
+// An error is returned if the format is unknown, or a *SyntaxError if a YAML or TOML file is malformed.
+func DecodeProductDataFormat(filePath string, format Format) (map[string]Product, error) {
+
+	// open file to read from
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return map[string]Product{}, err
+	}
+	defer file.Close()
+
+	return readProductsFormat(filePath, file, format)
+}
+
+// DecodeProductReaderFormat reads a product catalog in the given Format from r, as DecodeProductDataFormat does for a file.
+func DecodeProductReaderFormat(r io.Reader, format Format) (map[string]Product, error) {
+	return readProductsFormat("", r, format)
+}
+
+// readProductsFormat reads, decodes and validates a product catalog in the given Format from r, named name.
+// If format is "" the Format of the name is used. gzip and zstd compressed data is decompressed.
+func readProductsFormat(name string, r io.Reader, format Format) (map[string]Product, error) {
+
+	r, formatName, done, err := decompress(name, r)
+	if err != nil {
+		return map[string]Product{}, err
+	}
+	defer done()
+
+	if format == "" {
+		format = FormatOf(formatName)
+	}
+
+	switch format {
+	case FormatJSON:
+		return readProductsJSON(name, r)
+	case FormatCSV:
+		return readProductsCSV(name, r, CSVOptions{})
+	case FormatYAML, FormatTOML:
+	default:
+		return map[string]Product{}, fmt.Errorf("unknown products format %q, must be json, csv, yaml or toml", format)
+	}
+
+	// read into byte slice
+	byteSlice, err := io.ReadAll(r)
+
+	if err != nil {
+		return map[string]Product{}, err
+	}
+
+	// decode the catalog into plain values, and validate them as JSON
+	var catalog interface{}
+	if format == FormatYAML {
+		var node yaml.Node
+		err = yaml.Unmarshal(byteSlice, &node)
+		if err != nil {
+			err = &SyntaxError{Pos: Position{File: name}, Err: err}
+		}
+		catalog = yamlValue(&node)
+	} else {
+		tomlCatalog := map[string]interface{}{}
+		_, err = toml.Decode(string(byteSlice), &tomlCatalog)
+		catalog = tomlPrices(tomlCatalog)
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			err = &SyntaxError{Pos: position(name, byteSlice, int64(parseErr.Position.Start)), Err: err}
+		}
+	}
+	if err != nil {
+		return map[string]Product{}, err
+	}
+
+	data, err := json.Marshal(jsonValue(catalog))
+	if err != nil {
+		return map[string]Product{}, err
+	}
+	if bytes.Equal(data, []byte("null")) {
+		data = []byte("{}")
+	}
+
+	prodMap, err := decodeCatalog(data)
+	if err != nil {
+		return map[string]Product{}, err
+	}
+
+	return prodMap, nil
+}
+
+// yamlValue converts a YAML node into a value which can be encoded as JSON, with the keys of mappings as strings.
+//
+// Numbers keep their literal text (e.g. 12.00), so YAML prices are decoded exactly as the same JSON would be (see Product.UnmarshalJSON).
+func yamlValue(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case 0, yaml.DocumentNode:
+		// an empty document has no content, and is a zero node if there is no input
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m[node.Content[i].Value] = yamlValue(node.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		s := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			s[i] = yamlValue(item)
+		}
+		return s
+	}
+
+	// keep the text of numbers which are valid JSON, and decode other scalars (e.g. 0x1F or .inf) into their value
+	if tag := node.ShortTag(); tag == "!!int" || tag == "!!float" {
+		if number := strings.TrimPrefix(node.Value, "+"); json.Valid([]byte(number)) {
+			return json.Number(number)
+		}
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return node.Value
+	}
+	return value
+}
+
+// tomlPrices converts the float prices of a TOML catalog (Price, OfferPrice and the Price of each tier) into decimal numbers of major units.
+//
+// TOML floats always have a decimal point or exponent, but are decoded without their literal text, so unlike JSON and YAML
+// every float price is major units (e.g. 12.0 is £12), and integers are minor units.
+func tomlPrices(catalog map[string]interface{}) map[string]interface{} {
+	for _, value := range catalog {
+		prod, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, field := range prod {
+			switch {
+			case strings.EqualFold(key, "Price") || strings.EqualFold(key, "OfferPrice"):
+				prod[key] = tomlPrice(field)
+			case strings.EqualFold(key, "Tiers"):
+				switch tiers := field.(type) {
+				case []map[string]interface{}:
+					for _, tier := range tiers {
+						tomlTierPrice(tier)
+					}
+				case []interface{}:
+					for _, tier := range tiers {
+						if tier, ok := tier.(map[string]interface{}); ok {
+							tomlTierPrice(tier)
+						}
+					}
+				}
+			}
+		}
+	}
+	return catalog
+}
+
+// tomlTierPrice converts the float price of a TOML tier into a decimal number of major units.
+func tomlTierPrice(tier map[string]interface{}) {
+	for key, field := range tier {
+		if strings.EqualFold(key, "Price") {
+			tier[key] = tomlPrice(field)
+		}
+	}
+}
+
+// tomlPrice returns a float price as a decimal number of major units, with every decimal place of any currency
+// so it is not ambiguous (e.g. 12.0 as 12.000), and any other value unchanged.
+func tomlPrice(value interface{}) interface{} {
+	f, ok := value.(float64)
+	if !ok || math.IsInf(f, 0) || math.IsNaN(f) {
+		return value
+	}
+	number := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(number, ".") {
+		number += "."
+	}
+	if places := maxCurrencyExponent() - decimalPlaces(number); places > 0 {
+		number += strings.Repeat("0", places)
+	}
+	return json.Number(strings.TrimSuffix(number, "."))
+}
+
+// jsonValue converts a value decoded from YAML into one which can be encoded as JSON,
+// with the keys of YAML mappings (e.g. numeric product codes) converted to strings.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonValue(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+		return v
+	}
+	return value
+}
@@ -0,0 +1,131 @@
+package checkout_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_FormatOf tests the format of a product catalog is chosen by its file extension.
+func Test_FormatOf(t *testing.T) {
+	testCases := map[string]checkout.Format{
+		"products.json": checkout.FormatJSON,
+		"products.CSV":  checkout.FormatCSV,
+		"products.yaml": checkout.FormatYAML,
+		"products.yml":  checkout.FormatYAML,
+		"products.toml": checkout.FormatTOML,
+		"products":      checkout.FormatJSON,
+	}
+
+	for name, expected := range testCases {
+		if format := checkout.FormatOf(name); format != expected {
+			t.Errorf("expected format of %s: %s, got format: %s", name, expected, format)
+		}
+	}
+}
+
+// Test_DecodeProductDataFormat tests YAML and TOML catalogs decode into the same products as JSON, and are validated as JSON is.
+func Test_DecodeProductDataFormat(t *testing.T) {
+	expected := map[string]checkout.Product{
+		"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+		"B": {Price: 35, Rules: []checkout.RuleSpec{rule("multibuy", `{"Price":60,"Quantity":2}`)}},
+		"C": {Price: 25},
+		"D": {Price: 12},
+	}
+
+	testCases := []struct {
+		name     string
+		filePath string
+		format   checkout.Format
+		expErr   string
+	}{
+		{"1: YAML by extension", "../testdata/product_sets/21.yaml", "", ""},
+		{"2: TOML by extension", "../testdata/product_sets/22.toml", "", ""},
+		{"3: explicit format", "../testdata/product_sets/21.yaml", checkout.FormatYAML, ""},
+		{"4: explicit format not matching the file", "../testdata/product_sets/21.yaml", checkout.FormatJSON, "../testdata/product_sets/21.yaml:1:1: invalid character '#' looking for beginning of value"},
+		{"5: unknown format", "../testdata/product_sets/21.yaml", "xml", `unknown products format "xml", must be json, csv, yaml or toml`},
+		{
+			"6: invalid YAML products",
+			"../testdata/product_sets/23.yaml",
+			"",
+			`3 problems in product catalog: product "A": Offer: unknown field; product "B": Price: missing price; product "B": OfferPrice: offer price must be given with an offer quantity`,
+		},
+		{"7: malformed YAML", "../testdata/product_sets/24.yaml", "", "../testdata/product_sets/24.yaml: yaml: line 2: did not find expected key"},
+		{"8: malformed TOML", "../testdata/product_sets/25.toml", "", "../testdata/product_sets/25.toml:3:17: toml: line 4 (last key \"A.OfferQuantity\"): expected value but found '\\n' instead"},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeProductDataFormat(testCase.filePath, testCase.format)
+			if testCase.expErr != "" {
+				if s := checkout.FormatError(err); s != testCase.expErr {
+					t.Errorf("expected formatted error: %s, got formatted error: %s", testCase.expErr, s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got err: %v", err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("expected: %+v, got %+v", expected, result)
+			}
+		})
+	}
+
+	// check a catalog error is returned for invalid YAML products read from a reader
+	_, err := checkout.DecodeProductReaderFormat(strings.NewReader("A:\n  Price: 50\n  TaxClass: luxury\n"), checkout.FormatYAML)
+	var catalogErr *checkout.CatalogError
+	if !errors.As(err, &catalogErr) || len(catalogErr.Problems) != 1 || catalogErr.Problems[0].Path != "TaxClass" {
+		t.Errorf("expected catalog error for TaxClass, got err: %v", err)
+	}
+
+	// check decimal prices keep their decimal point, and YAML numbers follow the unit rules of JSON prices
+	decimalCases := []struct {
+		name     string
+		data     string
+		format   checkout.Format
+		expected checkout.Product
+		expErr   bool
+	}{
+		{"1: YAML decimal price", "A:\n  Price: 12.00\n  OfferQuantity: 2\n  OfferPrice: 2.00\n", checkout.FormatYAML, checkout.Product{Price: 1200, OfferQuantity: 2, OfferPrice: 200}, false},
+		{"2: YAML integer price", "A:\n  Price: 12\n", checkout.FormatYAML, checkout.Product{Price: 12}, false},
+		{"3: YAML ambiguous whole number decimal", "A:\n  Price: 12.0\n", checkout.FormatYAML, checkout.Product{}, true},
+		{"4: YAML decimal tier price", "A:\n  Price: 1.00\n  Tiers: [{MinQuantity: 10, Price: 0.80}]\n", checkout.FormatYAML, checkout.Product{Price: 100, Tiers: []checkout.Tier{{MinQuantity: 10, Price: 80}}}, false},
+		{"5: TOML decimal price", "[A]\nPrice = 12.00\nOfferQuantity = 2\nOfferPrice = 2.00\n", checkout.FormatTOML, checkout.Product{Price: 1200, OfferQuantity: 2, OfferPrice: 200}, false},
+		{"6: TOML integer price", "[A]\nPrice = 12\n", checkout.FormatTOML, checkout.Product{Price: 12}, false},
+		{"7: TOML float price is major units", "[A]\nPrice = 12.0\n", checkout.FormatTOML, checkout.Product{Price: 1200}, false},
+		{"8: TOML decimal tier price", "[A]\nPrice = 1.00\n[[A.Tiers]]\nMinQuantity = 10\nPrice = 0.80\n", checkout.FormatTOML, checkout.Product{Price: 100, Tiers: []checkout.Tier{{MinQuantity: 10, Price: 80}}}, false},
+		{"9: TOML float price in a three decimal currency", "[A]\nPrice = 1.25\nCurrency = \"KWD\"\n", checkout.FormatTOML, checkout.Product{Price: 1250, Currency: "KWD"}, false},
+		{"10: TOML price with too many decimal places", "[A]\nPrice = 0.505\n", checkout.FormatTOML, checkout.Product{}, true},
+	}
+	for _, testCase := range decimalCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeProductReaderFormat(strings.NewReader(testCase.data), testCase.format)
+			if (err != nil) != testCase.expErr {
+				t.Fatalf("expected error: %v, got err: %v", testCase.expErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(result["A"], testCase.expected) {
+				t.Errorf("expected: %+v, got %+v", testCase.expected, result["A"])
+			}
+		})
+	}
+
+	// check an empty YAML catalog has no products
+	if result, err := checkout.DecodeProductReaderFormat(strings.NewReader(""), checkout.FormatYAML); err != nil || len(result) != 0 {
+		t.Errorf("expected no products, got: %v, err: %v", result, err)
+	}
+
+	// check YAML and TOML catalogs are chosen by extension when pricing a checkout
+	for _, productsPath := range []string{"../testdata/product_sets/21.yaml", "../testdata/product_sets/22.toml"} {
+		total, err := checkout.ProcessCheckout("../testdata/checkout_sets/1.json", productsPath)
+		if err != nil || total != 284 {
+			t.Errorf("expected checkout price of: 284 with %s, got checkout price of: %d, err: %v", productsPath, total, err)
+		}
+	}
+}
@@ -2,27 +2,69 @@ package checkout
 
 import (
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
 )
 
 // DecodeCheckoutData takes a filePath and returns a slice of instances of CheckoutLine.
 //
 // An error is returned if the file cannot be read due to a non-existent file or invalid filePath,
 // or if the the files content is not JSON data capable of being being unmarshaled into []CheckoutLine
-// (i.e. it must be contain an array of objects with a product code and quantity value)
+// (i.e. it must be contain an array of objects with a product code and quantity value).
+// Malformed JSON is returned as a *SyntaxError, with the Position of the problem in the file.
+// A file with a .csv extension is read as CSV with the default CSVOptions, as by DecodeCheckoutCSV,
+// and a file with a .ndjson or .jsonl extension as NDJSON scans, as by DecodeCheckoutNDJSON.
+// The format of other files is found from their content, being a JSON array, NDJSON or CSV,
+// with a *SyntaxError listing the formats tried if it is none of these.
+// gzip and zstd compressed files (e.g. checkout.json.gz) are decompressed.
 func DecodeCheckoutData(filePath string) ([]CheckoutLine, error) {
 
-	// read file into byteSlice
-	byteSlice, err := ioutil.ReadFile(filePath)
+	// open file to read from
+	file, err := os.Open(filePath)
 
 	if err != nil {
 		return []CheckoutLine{}, err
 	}
+	defer file.Close()
 
-	// marshal data from byteSlice into a slice of CheckoutLine
-	cLSlice := []CheckoutLine{}
-	err = json.Unmarshal(byteSlice, &cLSlice)
+	cLSlice, _, err := readCheckout(filePath, file)
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+
+	return cLSlice, nil
+}
+
+// DecodeCheckoutReader reads checkout JSON from r and returns a slice of instances of CheckoutLine,
+// as DecodeCheckoutData does for a file. The Position of a *SyntaxError has no File.
+func DecodeCheckoutReader(r io.Reader) ([]CheckoutLine, error) {
+
+	cLSlice, _, err := readCheckout("", r)
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+
+	return cLSlice, nil
+}
+
+// DecodeCheckoutDataFS reads the named checkout JSON file from fsys and returns a slice of instances of CheckoutLine,
+// as DecodeCheckoutData does for a file path.
+func DecodeCheckoutDataFS(fsys fs.FS, name string) ([]CheckoutLine, error) {
+
+	// open file to read from
+	file, err := fsys.Open(name)
+
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+	defer file.Close()
 
+	cLSlice, _, err := readCheckout(name, file)
 	if err != nil {
 		return []CheckoutLine{}, err
 	}
@@ -30,27 +72,353 @@ func DecodeCheckoutData(filePath string) ([]CheckoutLine, error) {
 	return cLSlice, nil
 }
 
+// readCheckout reads and decodes checkout data from r, named name, returning its checkout lines and the Position of each.
+//
+// gzip and zstd compressed data is decompressed. Files with a .csv extension are read as CSV (see DecodeCheckoutCSV),
+// and .ndjson/ .jsonl files as NDJSON (see DecodeCheckoutNDJSON), the format of other files is found from their content (see sniffCheckout).
+func readCheckout(name string, r io.Reader) ([]CheckoutLine, []Position, error) {
+
+	r, formatName, done, err := decompress(name, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+
+	switch FormatOf(formatName) {
+	case FormatCSV:
+		return readCheckoutCSV(name, r, CSVOptions{})
+	case FormatNDJSON:
+		return readCheckoutNDJSON(name, r)
+	}
+
+	// read into byteSlice
+	byteSlice, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// decode data from byteSlice into a slice of CheckoutLine, in the format of its content
+	return sniffCheckout(name, byteSlice)
+}
+
 // DecodeProductData takes a filePath and returns a map of [productCode]Product.
 //
 // An error is returned if the file cannot be read but to a non-existent file or invalid filePath,
 // or if the files content is not JSON data capable of being unmarshaled into map[string]Product.
 // (i.e. it must contain an object using product code strings as keys to another object with Price/ OfferQuantity/ OfferPrice)
+// Malformed JSON is returned as a *SyntaxError, with the Position of the problem in the file.
+//
+// Prices may be given as integer minor units, or as decimal major units (see Product.UnmarshalJSON).
+//
+// Every product is validated before any are returned, with a *CatalogError listing every problem found,
+// with the product code and JSON path of each (see catalog.go). A product is invalid if it has an unknown field,
+// no Price, an OfferQuantity with no OfferPrice, a negative OfferQuantity, or invalid tiers, tax class, unit of measure,
+// currency or pricing rules.
+//
+// A file with a .csv extension is read as CSV with the default CSVOptions, as by DecodeProductCSV,
+// and a file with a .yaml, .yml or .toml extension as YAML or TOML (see DecodeProductDataFormat).
+// gzip and zstd compressed files (e.g. products.json.gz) are decompressed.
 func DecodeProductData(filePath string) (map[string]Product, error) {
 
-	// read file into byte slice
-	byteSlice, err := ioutil.ReadFile(filePath)
+	// open file to read from
+	file, err := os.Open(filePath)
 
 	if err != nil {
 		return map[string]Product{}, err
 	}
+	defer file.Close()
 
-	// marshal data from byteSlice into a map of [prodCodes]Product
-	prodMap := map[string]Product{}
-	err = json.Unmarshal(byteSlice, &prodMap)
+	return readProducts(filePath, file)
+}
+
+// DecodeProductReader reads products JSON from r and returns a map of [productCode]Product,
+// as DecodeProductData does for a file. The Position of a *SyntaxError has no File.
+func DecodeProductReader(r io.Reader) (map[string]Product, error) {
+	return readProducts("", r)
+}
+
+// DecodeProductDataFS reads the named products JSON file from fsys and returns a map of [productCode]Product,
+// as DecodeProductData does for a file path.
+func DecodeProductDataFS(fsys fs.FS, name string) (map[string]Product, error) {
+
+	// open file to read from
+	file, err := fsys.Open(name)
 
 	if err != nil {
 		return map[string]Product{}, err
 	}
+	defer file.Close()
+
+	return readProducts(name, file)
+}
+
+// readProducts reads, decodes and validates a product catalog from r, named name, in the Format of its extension (see FormatOf).
+func readProducts(name string, r io.Reader) (map[string]Product, error) {
+	return readProductsFormat(name, r, "")
+}
+
+// readProductsJSON reads, decodes and validates products JSON from r, named name.
+func readProductsJSON(name string, r io.Reader) (map[string]Product, error) {
+
+	// read into byte slice
+	byteSlice, err := io.ReadAll(r)
+
+	if err != nil {
+		return map[string]Product{}, err
+	}
+
+	// decode and validate data from byteSlice into a map of [prodCodes]Product
+	prodMap, err := decodeCatalog(byteSlice)
+
+	if err != nil {
+		// locate malformed JSON in the file
+		var catalogErr *CatalogError
+		if !errors.As(err, &catalogErr) {
+			err = syntaxError(name, byteSlice, 0, 0, err)
+		}
+		return map[string]Product{}, err
+	}
 
 	return prodMap, nil
 }
+
+// DecodeTaxTable takes a filePath and returns a TaxTable, to be used as the Tax of PricingOptions.
+//
+// An error is returned if the file cannot be read due to a non-existent file or invalid filePath,
+// if the files content is not JSON data capable of being unmarshaled into a TaxTable
+// (i.e. it must contain an object with the Rates of each tax class, and optionally Inclusive and Rounding),
+// or if the TaxTable is invalid.
+// Malformed JSON is returned as a *SyntaxError, with the Position of the problem in the file.
+func DecodeTaxTable(filePath string) (TaxTable, error) {
+
+	// open file to read from
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return TaxTable{}, err
+	}
+	defer file.Close()
+
+	return readTaxTable(filePath, file)
+}
+
+// DecodeTaxTableReader reads a tax table as JSON from r, as DecodeTaxTable does for a file. The Position of a *SyntaxError has no File.
+func DecodeTaxTableReader(r io.Reader) (TaxTable, error) {
+	return readTaxTable("", r)
+}
+
+// DecodeTaxTableFS reads the named tax table JSON file from fsys, as DecodeTaxTable does for a file path.
+func DecodeTaxTableFS(fsys fs.FS, name string) (TaxTable, error) {
+
+	// open file to read from
+	file, err := fsys.Open(name)
+
+	if err != nil {
+		return TaxTable{}, err
+	}
+	defer file.Close()
+
+	return readTaxTable(name, file)
+}
+
+// readTaxTable reads, decodes and validates a tax table as JSON from r, named name.
+func readTaxTable(name string, r io.Reader) (TaxTable, error) {
+
+	// read into byte slice
+	byteSlice, err := io.ReadAll(r)
+
+	if err != nil {
+		return TaxTable{}, err
+	}
+
+	// marshal data from byteSlice into a TaxTable, locating malformed JSON in the file
+	table := TaxTable{}
+	err = json.Unmarshal(byteSlice, &table)
+
+	if err != nil {
+		return TaxTable{}, syntaxError(name, byteSlice, 0, 0, err)
+	}
+
+	if err := table.Validate(); err != nil {
+		return TaxTable{}, err
+	}
+
+	return table, nil
+}
+
+// UnmarshalJSON decodes a Product from JSON, accepting its prices (Price, OfferPrice and the Price of each Tier) either as
+// an integer number of minor units (e.g. 50), or as a decimal number or string of major units (e.g. 0.50 or "0.50"),
+// which is converted exactly into minor units using the exponent of the product Currency (see ParseMoney).
+//
+// Only an unquoted integer is minor units, so a whole number of major units must be written with every decimal place of the currency
+// (e.g. 12.00 or "12.00" for £12), as 12.0 or "12" could be meant as either 12 or 1200 minor units, and are rejected as ambiguous.
+//
+// An error is returned if a decimal price has more decimal places than the currency allows, is an ambiguous whole number,
+// or a price is too large for an int.
+func (p *Product) UnmarshalJSON(data []byte) error {
+
+	// product has the fields of Product without its methods, so it is decoded without calling UnmarshalJSON,
+	// with the price fields of raw taking precedence over the embedded fields of the same name
+	type product Product
+	var raw struct {
+		product
+		Price      json.RawMessage
+		OfferPrice json.RawMessage
+		Tiers      []struct {
+			MinQuantity int
+			Price       json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	prod := Product(raw.product)
+	var err error
+
+	if prod.Price, err = decodePrice(raw.Price, prod.currency()); err != nil {
+		return &fieldError{Path: "Price", Err: err}
+	}
+	if prod.OfferPrice, err = decodePrice(raw.OfferPrice, prod.currency()); err != nil {
+		return &fieldError{Path: "OfferPrice", Err: err}
+	}
+	if raw.Tiers != nil {
+		prod.Tiers = make([]Tier, len(raw.Tiers))
+		for i, tier := range raw.Tiers {
+			prod.Tiers[i].MinQuantity = tier.MinQuantity
+			if prod.Tiers[i].Price, err = decodePrice(tier.Price, prod.currency()); err != nil {
+				return &fieldError{Path: fmt.Sprintf("Tiers[%d].Price", i), Err: err}
+			}
+		}
+	}
+
+	*p = prod
+
+	return nil
+}
+
+// decodePrice decodes a JSON price in the given currency into minor units,
+// integers being minor units, and decimal numbers or strings being major units. A missing or null price is 0.
+//
+// A decimal number or string which is a whole number of major units must give every decimal place of the currency,
+// otherwise it is ambiguous (e.g. 12.0 or "12" for GBP).
+func decodePrice(raw json.RawMessage, currencyCode string) (int, error) {
+
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var amount string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &amount); err != nil {
+			return 0, err
+		}
+	} else {
+		var number json.Number
+		if err := json.Unmarshal(raw, &number); err != nil {
+			return 0, err
+		}
+		amount = number.String()
+
+		// integers are already minor units
+		if !strings.ContainsAny(amount, ".eE") {
+			minor, err := strconv.ParseInt(amount, 10, 0)
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, fmt.Errorf("%w: %s is too large", ErrOverflow, amount)
+			}
+			return int(minor), err
+		}
+	}
+
+	money, err := ParseMoney(amount, currencyCode)
+	if err != nil {
+		return 0, err
+	}
+	if exponent, _ := CurrencyExponent(currencyCode); decimalPlaces(amount) < exponent && money.Amount%pow10(exponent) == 0 {
+		return 0, fmt.Errorf("price %s is ambiguous, give a whole number of major units with %d decimal places (e.g. %s), or minor units as an integer",
+			amount, exponent, money.Decimal())
+	}
+	if int64(int(money.Amount)) != money.Amount {
+		return 0, fmt.Errorf("%w: %s is too large", ErrOverflow, amount)
+	}
+
+	return int(money.Amount), nil
+}
+
+// decimalPlaces returns the number of digits after the decimal point of a decimal number.
+func decimalPlaces(amount string) int {
+	point := strings.IndexByte(amount, '.')
+	if point < 0 {
+		return 0
+	}
+	return len(amount) - point - 1
+}
+
+// pow10 returns 10 to the power of n.
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// DecodeBasketRules takes a filePath and returns a slice of RuleSpec referencing basket rules, to be used as the BasketRules of PricingOptions.
+//
+// An error is returned if the file cannot be read due to a non-existent file or invalid filePath,
+// or if the files content is not JSON data capable of being unmarshaled into []RuleSpec
+// (i.e. it must contain an array of objects with a rule Type and its Params)
+// Malformed JSON is returned as a *SyntaxError, with the Position of the problem in the file.
+func DecodeBasketRules(filePath string) ([]RuleSpec, error) {
+
+	// open file to read from
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return []RuleSpec{}, err
+	}
+	defer file.Close()
+
+	return readBasketRules(filePath, file)
+}
+
+// DecodeBasketRulesReader reads basket rules as JSON from r, as DecodeBasketRules does for a file. The Position of a *SyntaxError has no File.
+func DecodeBasketRulesReader(r io.Reader) ([]RuleSpec, error) {
+	return readBasketRules("", r)
+}
+
+// DecodeBasketRulesFS reads the named basket rules JSON file from fsys, as DecodeBasketRules does for a file path.
+func DecodeBasketRulesFS(fsys fs.FS, name string) ([]RuleSpec, error) {
+
+	// open file to read from
+	file, err := fsys.Open(name)
+
+	if err != nil {
+		return []RuleSpec{}, err
+	}
+	defer file.Close()
+
+	return readBasketRules(name, file)
+}
+
+// readBasketRules reads and decodes basket rules as JSON from r, named name.
+func readBasketRules(name string, r io.Reader) ([]RuleSpec, error) {
+
+	// read into byte slice
+	byteSlice, err := io.ReadAll(r)
+
+	if err != nil {
+		return []RuleSpec{}, err
+	}
+
+	// marshal data from byteSlice into a slice of RuleSpec, locating malformed JSON in the file
+	specs := []RuleSpec{}
+	err = json.Unmarshal(byteSlice, &specs)
+
+	if err != nil {
+		return []RuleSpec{}, syntaxError(name, byteSlice, 0, 0, err)
+	}
+
+	return specs, nil
+}
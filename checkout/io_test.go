@@ -1,8 +1,12 @@
 package checkout_test
 
 import (
+	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/billiem/checkout-system/checkout"
 )
@@ -20,10 +24,10 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			"1: given example case",
 			"../testdata/checkout_sets/1.json",
 			[]checkout.CheckoutLine{
-				{"A", 3},
-				{"B", 3},
-				{"C", 1},
-				{"D", 2},
+				{Code: "A", Quantity: 3},
+				{Code: "B", Quantity: 3},
+				{Code: "C", Quantity: 1},
+				{Code: "D", Quantity: 2},
 			},
 			false,
 		},
@@ -31,10 +35,10 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			"2: normal data",
 			"../testdata/checkout_sets/2.json",
 			[]checkout.CheckoutLine{
-				{"A", 4},
-				{"B", 1},
-				{"C", 2},
-				{"D", 6},
+				{Code: "A", Quantity: 4},
+				{Code: "B", Quantity: 1},
+				{Code: "C", Quantity: 2},
+				{Code: "D", Quantity: 6},
 			},
 			false,
 		},
@@ -42,9 +46,9 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			"3: only 3 lines",
 			"../testdata/checkout_sets/3.json",
 			[]checkout.CheckoutLine{
-				{"A", 4},
-				{"B", 0},
-				{"D", 2},
+				{Code: "A", Quantity: 4},
+				{Code: "B", Quantity: 0},
+				{Code: "D", Quantity: 2},
 			},
 			false,
 		},
@@ -52,10 +56,10 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			"4: all 0 quantity",
 			"../testdata/checkout_sets/4.json",
 			[]checkout.CheckoutLine{
-				{"A", 0},
-				{"B", 0},
-				{"C", 0},
-				{"D", 0},
+				{Code: "A", Quantity: 0},
+				{Code: "B", Quantity: 0},
+				{Code: "C", Quantity: 0},
+				{Code: "D", Quantity: 0},
 			},
 			false,
 		},
@@ -63,9 +67,9 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			"5: only 3 lines, higher vals, 1 qty 0",
 			"../testdata/checkout_sets/5.json",
 			[]checkout.CheckoutLine{
-				{"A", 94124},
-				{"B", 999991023},
-				{"C", 0},
+				{Code: "A", Quantity: 94124},
+				{Code: "B", Quantity: 999991023},
+				{Code: "C", Quantity: 0},
 			},
 			false,
 		},
@@ -73,10 +77,10 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			"6: 1 line with negative qty",
 			"../testdata/checkout_sets/6.json",
 			[]checkout.CheckoutLine{
-				{"A", 60},
-				{"B", 23},
-				{"C", 0},
-				{"D", -1},
+				{Code: "A", Quantity: 60},
+				{Code: "B", Quantity: 23},
+				{Code: "C", Quantity: 0},
+				{Code: "D", Quantity: -1},
 			},
 			false,
 		},
@@ -84,10 +88,10 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			"7: 4 lines with negative qty",
 			"../testdata/checkout_sets/7.json",
 			[]checkout.CheckoutLine{
-				{"A", -4},
-				{"B", -7},
-				{"C", -9},
-				{"D", -2},
+				{Code: "A", Quantity: -4},
+				{Code: "B", Quantity: -7},
+				{Code: "C", Quantity: -9},
+				{Code: "D", Quantity: -2},
 			},
 			false,
 		},
@@ -109,6 +113,16 @@ func Test_DecodeCheckoutData(t *testing.T) {
 			[]checkout.CheckoutLine{},
 			true,
 		},
+		{
+			"11: measured lines",
+			"../testdata/checkout_sets/9.json",
+			[]checkout.CheckoutLine{
+				{Code: "A", Quantity: 3},
+				{Code: "F", Measure: 1250},
+				{Code: "G", Measure: 333},
+			},
+			false,
+		},
 	}
 	for _, testCase := range testCases {
 		// run subtest for each testCase
@@ -234,6 +248,79 @@ func Test_DecodeProductData(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"6: tiered prices",
+			"../testdata/product_sets/9.json",
+			map[string]checkout.Product{
+				"A": {
+					Price: 50,
+					Tiers: []checkout.Tier{{MinQuantity: 10, Price: 45}, {MinQuantity: 50, Price: 40}},
+				},
+				"B": {
+					Price:    35,
+					Tiers:    []checkout.Tier{{MinQuantity: 2, Price: 30}},
+					TierMode: checkout.TierModeGraduated,
+				},
+			},
+			false,
+		},
+		{
+			"7: tiers out of order",
+			"../testdata/product_sets/10.json",
+			map[string]checkout.Product{},
+			true,
+		},
+		{
+			"8: decimal prices",
+			"../testdata/product_sets/11.json",
+			map[string]checkout.Product{
+				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+				"B": {Price: 35, OfferQuantity: 2, OfferPrice: 60},
+				"C": {Price: 25, Tiers: []checkout.Tier{{MinQuantity: 10, Price: 20}}},
+				"D": {Price: 1200, Currency: "JPY"},
+				"E": {Price: 1250, Currency: "KWD"},
+			},
+			false,
+		},
+		{
+			"9: decimal price with more precision than the currency",
+			"../testdata/product_sets/12.json",
+			map[string]checkout.Product{},
+			true,
+		},
+		{
+			"10: tax classes",
+			"../testdata/product_sets/13.json",
+			map[string]checkout.Product{
+				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+				"B": {Price: 35, OfferQuantity: 2, OfferPrice: 60, TaxClass: "reduced"},
+				"C": {Price: 25, TaxClass: "zero"},
+				"D": {Price: 12, TaxClass: "exempt"},
+			},
+			false,
+		},
+		{
+			"11: unknown tax class",
+			"../testdata/product_sets/14.json",
+			map[string]checkout.Product{},
+			true,
+		},
+		{
+			"12: products sold by weight and volume",
+			"../testdata/product_sets/15.json",
+			map[string]checkout.Product{
+				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+				"F": {Price: 240, Unit: "kg"},
+				"G": {Price: 110, Unit: "l", TaxClass: "zero"},
+			},
+			false,
+		},
+		{
+			"13: offer on a product sold by weight",
+			"../testdata/product_sets/16.json",
+			map[string]checkout.Product{},
+			true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -251,3 +338,271 @@ func Test_DecodeProductData(t *testing.T) {
 		})
 	}
 }
+
+// Tests the DecodeBasketRules function using data from testdata/basket_rules
+func Test_DecodeBasketRules(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		filePath string
+		expTypes []string
+		expErr   bool
+	}{
+		{
+			"1: spend threshold rules",
+			"../testdata/basket_rules/1.json",
+			[]string{"spendamountoff", "spendpercentoff"},
+			false,
+		},
+		{
+			"2: object rather than array",
+			"../testdata/basket_rules/2.json",
+			[]string{},
+			true,
+		},
+		{
+			"3: non-existent file",
+			"../testdata/basket_rules/fake.json",
+			[]string{},
+			true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// run subtest for each test case
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeBasketRules(testCase.filePath)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("case: %s, expected err: %v, got err: %v", testCase.name, testCase.expErr, err)
+			}
+			// check returned rule types equal to expected
+			types := []string{}
+			for _, spec := range result {
+				types = append(types, spec.Type)
+			}
+			if !reflect.DeepEqual(types, testCase.expTypes) {
+				t.Errorf("case: %s, expected rule types: %v, got: %v", testCase.name, testCase.expTypes, types)
+			}
+		})
+	}
+}
+
+// Tests the UnmarshalJSON method of Product, decoding prices as integer minor units, or decimal major units
+func Test_ProductUnmarshalJSON(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		data     string
+		expected checkout.Product
+		expErr   bool
+	}{
+		{"1: integer minor units", `{"Price": 50, "OfferQuantity": 3, "OfferPrice": 140}`, checkout.Product{Price: 50, OfferQuantity: 3, OfferPrice: 140}, false},
+		{"2: decimal number", `{"Price": 0.5}`, checkout.Product{Price: 50}, false},
+		{"3: decimal string", `{"Price": "12.99"}`, checkout.Product{Price: 1299}, false},
+		{"4: whole number string is ambiguous", `{"Price": "12"}`, checkout.Product{}, true},
+		{"5: negative decimal", `{"Price": -0.25}`, checkout.Product{Price: -25}, false},
+		{"6: null price", `{"Price": null}`, checkout.Product{}, false},
+		{"7: currency exponent", `{"Price": "1.5", "Currency": "KWD"}`, checkout.Product{Price: 1500, Currency: "KWD"}, false},
+		{"8: too many decimal places", `{"Price": 0.505}`, checkout.Product{}, true},
+		{"9: decimal with unsupported currency", `{"Price": "0.50", "Currency": "XYZ"}`, checkout.Product{}, true},
+		{"10: integer too large", `{"Price": 9223372036854775808}`, checkout.Product{}, true},
+		{"11: invalid tier price", `{"Price": 50, "Tiers": [{"MinQuantity": 2, "Price": "abc"}]}`, checkout.Product{}, true},
+		{"12: price is not a number", `{"Price": true}`, checkout.Product{}, true},
+		{"13: integer is minor units", `{"Price": 12}`, checkout.Product{Price: 12}, false},
+		{"14: whole number decimal is ambiguous", `{"Price": 12.0}`, checkout.Product{}, true},
+		{"15: whole number decimal with every decimal place is major units", `{"Price": 12.00}`, checkout.Product{Price: 1200}, false},
+		{"16: whole number string with every decimal place is major units", `{"Price": "12.00"}`, checkout.Product{Price: 1200}, false},
+		{"17: whole number string in a currency without decimal places", `{"Price": "1200", "Currency": "JPY"}`, checkout.Product{Price: 1200, Currency: "JPY"}, false},
+	}
+
+	for _, testCase := range testCases {
+		// run subtest for each test case
+		t.Run(testCase.name, func(t *testing.T) {
+			var prod checkout.Product
+			err := json.Unmarshal([]byte(testCase.data), &prod)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("case: %s, expected err: %v, got err: %v", testCase.name, testCase.expErr, err)
+			}
+			// check decoded Product equal to expected
+			if err == nil && !reflect.DeepEqual(prod, testCase.expected) {
+				t.Errorf("case: %s, expected product: %+v, got product: %+v", testCase.name, testCase.expected, prod)
+			}
+		})
+	}
+}
+
+// Tests the DecodeTaxTable function using data from testdata/tax_tables
+func Test_DecodeTaxTable(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		filePath string
+		expected checkout.TaxTable
+		expErr   bool
+	}{
+		{
+			"1: tax exclusive rates",
+			"../testdata/tax_tables/1.json",
+			checkout.TaxTable{Rates: map[string]checkout.TaxRate{"standard": 2000, "reduced": 500}},
+			false,
+		},
+		{
+			"2: tax inclusive rates rounded per invoice",
+			"../testdata/tax_tables/2.json",
+			checkout.TaxTable{Rates: map[string]checkout.TaxRate{"standard": 2000, "reduced": 500, "zero": 0}, Inclusive: true, Rounding: "invoice"},
+			false,
+		},
+		{
+			"3: rate for exempt tax class",
+			"../testdata/tax_tables/3.json",
+			checkout.TaxTable{},
+			true,
+		},
+		{
+			"4: non-existent file",
+			"../testdata/tax_tables/fake.json",
+			checkout.TaxTable{},
+			true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// run subtest for each test case
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeTaxTable(testCase.filePath)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("case: %s, expected err: %v, got err: %v", testCase.name, testCase.expErr, err)
+			}
+			// check returned TaxTable equal to expected
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("case: %s, expected tax table: %+v, got: %+v", testCase.name, testCase.expected, result)
+			}
+		})
+	}
+}
+
+// errReader is an io.Reader which always fails.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+// Tests the DecodeCheckoutReader, DecodeProductReader, DecodeTaxTableReader and DecodeBasketRulesReader functions, reading JSON from in-memory readers
+func Test_DecodeReader(t *testing.T) {
+
+	cLSlice, err := checkout.DecodeCheckoutReader(strings.NewReader(`[{"code": "A", "quantity": 3}, {"code": "B", "quantity": 1}]`))
+	if err != nil {
+		t.Fatalf("expected no error, got err: %v", err)
+	}
+	if expected := []checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 1}}; !reflect.DeepEqual(cLSlice, expected) {
+		t.Errorf("expected checkout lines: %v, got checkout lines: %v", expected, cLSlice)
+	}
+
+	products, err := checkout.DecodeProductReader(strings.NewReader(`{"A": {"Price": 50, "OfferQuantity": 3, "OfferPrice": 140}}`))
+	if err != nil {
+		t.Fatalf("expected no error, got err: %v", err)
+	}
+	if expected := map[string]checkout.Product{"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140}}; !reflect.DeepEqual(products, expected) {
+		t.Errorf("expected products: %v, got products: %v", expected, products)
+	}
+
+	// check malformed JSON is located without a file name
+	_, err = checkout.DecodeCheckoutReader(strings.NewReader("[\n    {\"code\": \"A\",}\n]"))
+	if s := checkout.FormatError(err); s != "2:18: invalid character '}' looking for beginning of object key string" {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+
+	table, err := checkout.DecodeTaxTableReader(strings.NewReader(`{"Rates": {"standard": 20}}`))
+	if expected := (checkout.TaxTable{Rates: map[string]checkout.TaxRate{"standard": 2000}}); err != nil || !reflect.DeepEqual(table, expected) {
+		t.Errorf("expected tax table: %+v, got tax table: %+v, err: %v", expected, table, err)
+	}
+	specs, err := checkout.DecodeBasketRulesReader(strings.NewReader(`[{"Type": "spendpercentoff", "Params": {"Threshold": 100, "Percent": 10}}]`))
+	if err != nil || len(specs) != 1 || specs[0].Type != "spendpercentoff" {
+		t.Errorf("unexpected basket rules: %v, err: %v", specs, err)
+	}
+
+	// check malformed tax tables and basket rules are located without a file name
+	_, err = checkout.DecodeTaxTableReader(strings.NewReader("{\n    \"Rates\": {\"standard\": 20,}\n}"))
+	if s := checkout.FormatError(err); s != "2:30: invalid character '}' looking for beginning of object key string" {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+	_, err = checkout.DecodeBasketRulesReader(strings.NewReader("[\n    {\"Type\": \"spendpercentoff\",}\n]"))
+	if s := checkout.FormatError(err); s != "2:32: invalid character '}' looking for beginning of object key string" {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+
+	// check errors reading are returned
+	if _, err := checkout.DecodeTaxTableReader(errReader{}); err == nil {
+		t.Errorf("expected read error, got nil")
+	}
+	if _, err := checkout.DecodeBasketRulesReader(errReader{}); err == nil {
+		t.Errorf("expected read error, got nil")
+	}
+	if _, err := checkout.DecodeCheckoutReader(errReader{}); err == nil {
+		t.Errorf("expected read error, got nil")
+	}
+	if _, err := checkout.DecodeProductReader(errReader{}); err == nil {
+		t.Errorf("expected read error, got nil")
+	}
+}
+
+// Tests the DecodeCheckoutDataFS, DecodeProductDataFS, DecodeTaxTableFS and DecodeBasketRulesFS functions, reading JSON files from an fs.FS
+func Test_DecodeFS(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"checkout.json":      {Data: []byte(`[{"code": "A", "quantity": 3}]`)},
+		"data/products.json": {Data: []byte(`{"A": {"Price": 50}}`)},
+		"bad.json":           {Data: []byte(`[{"code": "A", "quantity": }]`)},
+		"tax.json":           {Data: []byte(`{"Rates": {"standard": 20}, "Inclusive": true}`)},
+		"rules.json":         {Data: []byte(`[{"Type": "spendamountoff", "Params": {"Threshold": 200, "Amount": 20}}]`)},
+		"bad_tax.json":       {Data: []byte(`{"Rates": {"standard": }}`)},
+	}
+
+	cLSlice, err := checkout.DecodeCheckoutDataFS(fsys, "checkout.json")
+	if err != nil || !reflect.DeepEqual(cLSlice, []checkout.CheckoutLine{{Code: "A", Quantity: 3}}) {
+		t.Errorf("unexpected checkout lines: %v, err: %v", cLSlice, err)
+	}
+	products, err := checkout.DecodeProductDataFS(fsys, "data/products.json")
+	if err != nil || !reflect.DeepEqual(products, map[string]checkout.Product{"A": {Price: 50}}) {
+		t.Errorf("unexpected products: %v, err: %v", products, err)
+	}
+
+	table, err := checkout.DecodeTaxTableFS(fsys, "tax.json")
+	if err != nil || !table.Inclusive || table.Rates["standard"] != 2000 {
+		t.Errorf("unexpected tax table: %+v, err: %v", table, err)
+	}
+	specs, err := checkout.DecodeBasketRulesFS(fsys, "rules.json")
+	if err != nil || len(specs) != 1 || specs[0].Type != "spendamountoff" {
+		t.Errorf("unexpected basket rules: %v, err: %v", specs, err)
+	}
+
+	// check malformed JSON is located in the named file
+	_, err = checkout.DecodeTaxTableFS(fsys, "bad_tax.json")
+	var taxSyntaxErr *checkout.SyntaxError
+	if !errors.As(err, &taxSyntaxErr) || taxSyntaxErr.Pos.String() != "bad_tax.json:1:24" {
+		t.Errorf("expected *SyntaxError at bad_tax.json:1:24, got err: %v", err)
+	}
+	_, err = checkout.DecodeCheckoutDataFS(fsys, "bad.json")
+	var syntaxErr *checkout.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Pos.String() != "bad.json:1:28" {
+		t.Errorf("expected *SyntaxError at bad.json:1:28, got err: %v", err)
+	}
+
+	// check missing files return an error
+	if _, err := checkout.DecodeCheckoutDataFS(fsys, "fake.json"); err == nil {
+		t.Errorf("expected error for non-existent file, got nil")
+	}
+	if _, err := checkout.DecodeProductDataFS(fsys, "fake.json"); err == nil {
+		t.Errorf("expected error for non-existent file, got nil")
+	}
+	if _, err := checkout.DecodeTaxTableFS(fsys, "fake.json"); err == nil {
+		t.Errorf("expected error for non-existent file, got nil")
+	}
+	if _, err := checkout.DecodeBasketRulesFS(fsys, "fake.json"); err == nil {
+		t.Errorf("expected error for non-existent file, got nil")
+	}
+}
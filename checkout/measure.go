@@ -0,0 +1,122 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Units of measure of a Product, products sold by weight or volume are priced per kilogram, gram or litre of a measured quantity.
+const (
+	UnitEach     = "each"
+	UnitKilogram = "kg"
+	UnitGram     = "g"
+	UnitLitre    = "l"
+)
+
+// MeasurePlaces is the number of decimal places of a Measure.
+const MeasurePlaces = 3
+
+// Measure is a measured quantity of a product sold by weight or volume, in thousandths of its Unit (e.g. 1250 is 1.25kg),
+// given in JSON as a decimal number or string with at most MeasurePlaces decimal places (e.g. 1.25 or "1.250").
+type Measure int64
+
+// UnmarshalJSON decodes a Measure from a JSON decimal number or string, with null leaving the measure unchanged as for other types.
+func (m *Measure) UnmarshalJSON(data []byte) error {
+
+	if string(data) == "null" {
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+
+	measure, err := parseDecimal(number.String(), MeasurePlaces)
+	if err != nil {
+		return fmt.Errorf("measure: %w", err)
+	}
+	*m = Measure(measure)
+
+	return nil
+}
+
+// String formats the measure as a decimal number without trailing zeros (e.g. "1.25").
+func (m Measure) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+	}
+	whole := strconv.FormatUint(absInt64(int64(m))/1000, 10)
+	if fraction := absInt64(int64(m)) % 1000; fraction != 0 {
+		whole += "." + strings.TrimRight(fmt.Sprintf("%03d", fraction), "0")
+	}
+	return sign + whole
+}
+
+// absInt64 returns the magnitude of n, which can be represented for the most negative int64.
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// unit returns the unit of measure of the product, or UnitEach if not given.
+func (p Product) unit() string {
+	if p.Unit == "" {
+		return UnitEach
+	}
+	return p.Unit
+}
+
+// Measured returns whether or not the product is sold by weight or volume, rather than each.
+func (p Product) Measured() bool {
+	return p.unit() != UnitEach
+}
+
+// validateUnit checks the unit of measure of the product is known, and that a measured product has no pricing rules,
+// as multibuy offers, tiers and other rules count whole items which a measured quantity does not have.
+func (p Product) validateUnit() error {
+
+	switch p.unit() {
+	case UnitEach:
+		return nil
+	case UnitKilogram, UnitGram, UnitLitre:
+	default:
+		return fmt.Errorf("unknown unit of measure %q", p.Unit)
+	}
+
+	if p.OfferQuantity != 0 || len(p.Tiers) > 0 || len(p.Rules) > 0 {
+		return errors.New("offers, tiers and pricing rules cannot be used with a product sold by weight or volume")
+	}
+
+	return nil
+}
+
+// lineGross returns the normal price of a checkout line of a product, and false if the price overflows.
+//
+// Products sold each are priced at Quantity times Price. Products sold by weight or volume are priced at Measure times Price,
+// rounded to the nearest minor unit with halves rounded away from zero.
+func lineGross(cL CheckoutLine, prod Product) (int64, bool) {
+
+	if !prod.Measured() {
+		var c checked
+		gross := c.mul(int64(cL.Quantity), int64(prod.Price))
+		return gross, !c.overflow
+	}
+
+	numerator := new(big.Int).Mul(big.NewInt(int64(cL.Measure)), big.NewInt(int64(prod.Price)))
+	if numerator.Sign() < 0 {
+		numerator.Sub(numerator, big.NewInt(500))
+	} else {
+		numerator.Add(numerator, big.NewInt(500))
+	}
+	gross := numerator.Quo(numerator, big.NewInt(1000))
+
+	return gross.Int64(), gross.IsInt64()
+}
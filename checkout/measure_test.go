@@ -0,0 +1,190 @@
+package checkout_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_Measure tests decoding a Measure from a JSON decimal number or string, and formatting it without trailing zeros.
+func Test_Measure(t *testing.T) {
+	testCases := []struct {
+		name      string
+		data      string
+		expected  checkout.Measure
+		expString string
+		expErr    bool
+	}{
+		{"1: whole number", `2`, 2000, "2", false},
+		{"2: decimal number", `1.25`, 1250, "1.25", false},
+		{"3: decimal string", `"0.333"`, 333, "0.333", false},
+		{"4: trailing zeros", `"1.500"`, 1500, "1.5", false},
+		{"5: zero", `0`, 0, "0", false},
+		{"6: negative", `-0.5`, -500, "-0.5", false},
+		{"7: too many decimal places", `1.2345`, 0, "", true},
+		{"8: not a number", `"heavy"`, 0, "", true},
+		{"9: null", `null`, 0, "0", false},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			var measure checkout.Measure
+			err := json.Unmarshal([]byte(testCase.data), &measure)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %v", testCase.expErr, err)
+			}
+			if err == nil && (measure != testCase.expected || measure.String() != testCase.expString) {
+				t.Errorf("expected measure: %d (%s), got measure: %d (%s)", testCase.expected, testCase.expString, measure, measure)
+			}
+		})
+	}
+
+	// check null leaves a measure unchanged
+	measure := checkout.Measure(1250)
+	if err := json.Unmarshal([]byte(`null`), &measure); err != nil || measure != 1250 {
+		t.Errorf("expected null to leave measure 1250, got measure: %d, err: %v", measure, err)
+	}
+}
+
+// Test_PriceCheckoutMeasured tests pricing checkouts with products sold by weight or volume,
+// checking the rounding of measured lines and that lines must be counted or measured to match their product.
+func Test_PriceCheckoutMeasured(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+		"F": {Price: 240, Unit: checkout.UnitKilogram},
+		"G": {Price: 110, Unit: checkout.UnitLitre, TaxClass: checkout.TaxClassZero},
+		"H": {Price: 1, Unit: checkout.UnitGram},
+		"I": {Price: -1, Unit: checkout.UnitGram},
+		"J": {Price: 240, Unit: "lb"},
+		"K": {Price: 240, Unit: checkout.UnitKilogram, Tiers: []checkout.Tier{{MinQuantity: 2, Price: 200}}},
+		"L": {Price: 10000, Unit: checkout.UnitKilogram},
+	}
+
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		expGross      []int64
+		expTotal      int64
+		expErr        bool
+	}{
+		{
+			"1: counted and measured lines",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "F", Measure: 1250}, {Code: "G", Measure: 333}},
+			[]int64{150, 300, 37},
+			477,
+			false,
+		},
+		{
+			"2: measured lines of the same product",
+			[]checkout.CheckoutLine{{Code: "F", Measure: 500}, {Code: "F", Measure: 750}},
+			[]int64{120, 180},
+			300,
+			false,
+		},
+		{
+			"3: halves rounded away from zero",
+			[]checkout.CheckoutLine{{Code: "H", Measure: 500}, {Code: "I", Measure: 500}},
+			[]int64{1, -1},
+			0,
+			false,
+		},
+		{
+			"4: measured product with a quantity",
+			[]checkout.CheckoutLine{{Code: "F", Quantity: 1}},
+			nil,
+			0,
+			true,
+		},
+		{
+			"5: counted product with a measure",
+			[]checkout.CheckoutLine{{Code: "A", Measure: 1000}},
+			nil,
+			0,
+			true,
+		},
+		{
+			"6: negative measure",
+			[]checkout.CheckoutLine{{Code: "F", Measure: -1000}},
+			nil,
+			0,
+			true,
+		},
+		{
+			"7: unknown unit of measure",
+			[]checkout.CheckoutLine{{Code: "J", Measure: 1000}},
+			nil,
+			0,
+			true,
+		},
+		{
+			"8: tiers on a measured product",
+			[]checkout.CheckoutLine{{Code: "K", Measure: 1000}},
+			nil,
+			0,
+			true,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			receipt, err := checkout.PriceCheckout(testCase.checkoutLines, products, checkout.PricingOptions{})
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Fatalf("expected error: %v, got err: %v", testCase.expErr, err)
+			}
+			if err != nil {
+				return
+			}
+			// compare gross of each line and total to expected vals
+			gross := []int64{}
+			for _, line := range receipt.Lines {
+				gross = append(gross, line.Gross.Amount)
+			}
+			if !reflect.DeepEqual(gross, testCase.expGross) {
+				t.Errorf("expected line gross: %v, got line gross: %v", testCase.expGross, gross)
+			}
+			if receipt.Total != gbp(testCase.expTotal) {
+				t.Errorf("expected checkout price of: %d, got checkout price of: %v", testCase.expTotal, receipt.Total)
+			}
+		})
+	}
+
+	// check a basket adjustment is divided between counted and measured products for tax
+	receipt, err := checkout.PriceCheckout(
+		[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "F", Measure: 1250}, {Code: "G", Measure: 333}},
+		products,
+		checkout.PricingOptions{
+			BasketRules: []checkout.RuleSpec{rule("spendamountoff", `{"Threshold": 0, "Amount": 47}`)},
+			Tax:         &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: 2000}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got err: %s", err)
+	}
+	expTaxes := []checkout.TaxTotal{
+		{Class: "standard", Rate: 2000, Taxable: gbp(396), Tax: gbp(79)},
+		{Class: "zero", Rate: 0, Taxable: gbp(34), Tax: gbp(0)},
+	}
+	if !reflect.DeepEqual(receipt.Taxes, expTaxes) {
+		t.Errorf("expected taxes: %+v, got taxes: %+v", expTaxes, receipt.Taxes)
+	}
+	if receipt.Total != gbp(509) {
+		t.Errorf("expected checkout price of: 509, got checkout price of: %v", receipt.Total)
+	}
+
+	// check a measured line too large to price returns an *OverflowError
+	var overflowErr *checkout.OverflowError
+	_, err = checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "L", Measure: math.MaxInt64}}, products, checkout.PricingOptions{})
+	if !errors.As(err, &overflowErr) || overflowErr.Line != 1 || overflowErr.Amount != "line total" {
+		t.Errorf("expected line total overflow error, got err: %v", err)
+	}
+}
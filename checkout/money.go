@@ -0,0 +1,235 @@
+package checkout
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of a Product which does not give a Currency.
+const DefaultCurrency = "GBP"
+
+// ErrCurrencyMismatch is returned (wrapped) when amounts of money in different currencies are combined.
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// currency holds the symbol used to format amounts of an ISO 4217 currency, and its exponent (the number of minor unit digits).
+// Currencies without a symbol are formatted with their currency code following the amount (e.g. "1.250 KWD").
+type currency struct {
+	symbol   string
+	exponent int
+}
+
+// currencies maps the supported ISO 4217 currency codes to their currency
+var currencies = map[string]currency{
+	"AUD": {"A$", 2},
+	"BHD": {"", 3},
+	"CAD": {"C$", 2},
+	"CHF": {"", 2},
+	"DKK": {"", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"ISK": {"", 0},
+	"JOD": {"", 3},
+	"JPY": {"¥", 0},
+	"KRW": {"₩", 0},
+	"KWD": {"", 3},
+	"NOK": {"", 2},
+	"NZD": {"NZ$", 2},
+	"OMR": {"", 3},
+	"PLN": {"", 2},
+	"SEK": {"", 2},
+	"USD": {"$", 2},
+}
+
+// CurrencyExponent returns the number of minor unit digits of an ISO 4217 currency code (e.g. 2 for GBP, 0 for JPY),
+// and whether or not the currency is supported.
+func CurrencyExponent(code string) (int, bool) {
+	c, ok := currencies[code]
+	return c.exponent, ok
+}
+
+// maxCurrencyExponent returns the largest exponent of the supported currencies.
+func maxCurrencyExponent() int {
+	max := 0
+	for _, c := range currencies {
+		if c.exponent > max {
+			max = c.exponent
+		}
+	}
+	return max
+}
+
+// ParseMoney parses a decimal amount in the major units of a currency (e.g. "12.99" or "-0.5"), returning it as Money in minor units.
+//
+// The amount is converted exactly, without floating point rounding. An error is returned if the currency is unsupported,
+// if the amount is not a decimal number, if it has more decimal places than the exponent of the currency (other than trailing zeros),
+// or if it is too large to be represented (wrapping ErrOverflow).
+func ParseMoney(amount string, currencyCode string) (Money, error) {
+
+	exponent, ok := CurrencyExponent(currencyCode)
+	if !ok {
+		return Money{}, fmt.Errorf("unsupported currency %q", currencyCode)
+	}
+
+	minor, err := parseDecimal(amount, exponent)
+	if err != nil {
+		return Money{}, fmt.Errorf("%s amount: %w", currencyCode, err)
+	}
+
+	return Money{Amount: minor, Currency: currencyCode}, nil
+}
+
+// parseDecimal parses a decimal number (e.g. "12.99"), returning it as an integer scaled by 10^places (e.g. 1299 for 2 places).
+//
+// An error is returned if s is not a decimal number, if it has more than places decimal places (other than trailing zeros),
+// or if it is too large to be represented (wrapping ErrOverflow).
+func parseDecimal(s string, places int) (int64, error) {
+
+	digits := s
+	negative := false
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		negative, digits = digits[0] == '-', digits[1:]
+	}
+
+	// split the whole and fractional digits
+	whole, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, fraction = digits[:i], digits[i+1:]
+	}
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%q is not a decimal number", s)
+	}
+	if len(fraction) > places {
+		if strings.Trim(fraction[places:], "0") != "" {
+			return 0, fmt.Errorf("%q has more than %d decimal places", s, places)
+		}
+		fraction = fraction[:places]
+	}
+	fraction += strings.Repeat("0", places-len(fraction))
+
+	// accumulate the scaled value, subtracting the digits of negative numbers so the most negative int64 can be parsed
+	var c checked
+	scaled := int64(0)
+	for _, digit := range whole + fraction {
+		if negative {
+			scaled = c.sub(c.mul(scaled, 10), int64(digit-'0'))
+		} else {
+			scaled = c.add(c.mul(scaled, 10), int64(digit-'0'))
+		}
+	}
+	if c.overflow {
+		return 0, fmt.Errorf("%w: %q is too large", ErrOverflow, s)
+	}
+
+	return scaled, nil
+}
+
+// isDigits returns whether or not a string only contains the digits 0-9.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Money is an amount of money in the minor units of a currency, along with its ISO 4217 currency code
+// (e.g. {284, "GBP"} is £2.84).
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// Add returns the sum of two amounts of money.
+//
+// An error wrapping ErrCurrencyMismatch is returned if the amounts are in different currencies,
+// or wrapping ErrOverflow if the sum is too large to be represented.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: cannot add %s to %s", ErrCurrencyMismatch, o.Currency, m.Currency)
+	}
+	var c checked
+	sum := c.add(m.Amount, o.Amount)
+	if c.overflow {
+		return Money{}, fmt.Errorf("%w: cannot add %s to %s", ErrOverflow, o, m)
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns the difference of two amounts of money.
+//
+// An error wrapping ErrCurrencyMismatch is returned if the amounts are in different currencies,
+// or wrapping ErrOverflow if the difference is too large to be represented.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: cannot subtract %s from %s", ErrCurrencyMismatch, o.Currency, m.Currency)
+	}
+	var c checked
+	diff := c.sub(m.Amount, o.Amount)
+	if c.overflow {
+		return Money{}, fmt.Errorf("%w: cannot subtract %s from %s", ErrOverflow, o, m)
+	}
+	return Money{Amount: diff, Currency: m.Currency}, nil
+}
+
+// Mul returns the amount of money multiplied by n.
+//
+// An error wrapping ErrOverflow is returned if the product is too large to be represented.
+func (m Money) Mul(n int64) (Money, error) {
+	var c checked
+	product := c.mul(m.Amount, n)
+	if c.overflow {
+		return Money{}, fmt.Errorf("%w: cannot multiply %s by %d", ErrOverflow, m, n)
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Neg returns the amount of money negated.
+// The most negative amount cannot be negated, and is returned unchanged.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// String formats the amount in the major units of its currency (e.g. "£2.84", "-€0.50", "¥284" or "1.250 KWD").
+//
+// Unsupported currencies are formatted as minor units followed by the currency code (e.g. "284 XYZ").
+func (m Money) String() string {
+
+	c, ok := currencies[m.Currency]
+	if !ok {
+		return strconv.FormatInt(m.Amount, 10) + " " + m.Currency
+	}
+
+	sign, digits := "", m.Decimal()
+	if m.Amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	if c.symbol == "" {
+		return sign + digits + " " + m.Currency
+	}
+	return sign + c.symbol + digits
+}
+
+// Decimal formats the amount as a decimal number in the major units of its currency, without a symbol or currency code
+// (e.g. "2.84", "-0.50" or "284" for JPY). Amounts of unsupported currencies are formatted in minor units.
+func (m Money) Decimal() string {
+
+	digits := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	// pad with leading zeros so there is at least one major unit digit, and split off the minor unit digits
+	if exponent := currencies[m.Currency].exponent; exponent > 0 {
+		if len(digits) <= exponent {
+			digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+	}
+
+	return sign + digits
+}
@@ -0,0 +1,211 @@
+package checkout_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// gbp returns an amount of pence as Money in the default currency.
+func gbp(amount int64) checkout.Money {
+	return checkout.Money{Amount: amount, Currency: checkout.DefaultCurrency}
+}
+
+// Test_MoneyString tests formatting amounts of money in the major units of their currency.
+func Test_MoneyString(t *testing.T) {
+	testCases := []struct {
+		name     string
+		money    checkout.Money
+		expected string
+	}{
+		{"1: pounds and pence", checkout.Money{Amount: 284, Currency: "GBP"}, "£2.84"},
+		{"2: pence only", checkout.Money{Amount: 5, Currency: "GBP"}, "£0.05"},
+		{"3: zero", checkout.Money{Amount: 0, Currency: "GBP"}, "£0.00"},
+		{"4: negative", checkout.Money{Amount: -50, Currency: "EUR"}, "-€0.50"},
+		{"5: large negative", checkout.Money{Amount: -111087, Currency: "GBP"}, "-£1110.87"},
+		{"6: zero exponent", checkout.Money{Amount: 284, Currency: "JPY"}, "¥284"},
+		{"7: three digit exponent without symbol", checkout.Money{Amount: 1250, Currency: "KWD"}, "1.250 KWD"},
+		{"8: unsupported currency", checkout.Money{Amount: 284, Currency: "XYZ"}, "284 XYZ"},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			if s := testCase.money.String(); s != testCase.expected {
+				t.Errorf("expected money string: %q, got money string: %q", testCase.expected, s)
+			}
+		})
+	}
+}
+
+// Test_MoneyDecimal tests formatting amounts of money as decimal numbers, without a symbol or currency code.
+func Test_MoneyDecimal(t *testing.T) {
+	testCases := []struct {
+		name     string
+		money    checkout.Money
+		expected string
+	}{
+		{"1: pounds and pence", checkout.Money{Amount: 284, Currency: "GBP"}, "2.84"},
+		{"2: negative pence only", checkout.Money{Amount: -5, Currency: "EUR"}, "-0.05"},
+		{"3: zero exponent", checkout.Money{Amount: 284, Currency: "JPY"}, "284"},
+		{"4: three digit exponent", checkout.Money{Amount: 1250, Currency: "KWD"}, "1.250"},
+		{"5: unsupported currency", checkout.Money{Amount: 284, Currency: "XYZ"}, "284"},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			if s := testCase.money.Decimal(); s != testCase.expected {
+				t.Errorf("expected decimal: %q, got decimal: %q", testCase.expected, s)
+			}
+		})
+	}
+}
+
+// Test_MoneyArithmetic tests adding and subtracting amounts of money, and checks combining different currencies returns ErrCurrencyMismatch.
+func Test_MoneyArithmetic(t *testing.T) {
+	sum, err := gbp(284).Add(gbp(20))
+	if err != nil || sum != gbp(304) {
+		t.Errorf("expected sum of: £3.04, got sum of: %v, err: %v", sum, err)
+	}
+	diff, err := gbp(284).Sub(gbp(20))
+	if err != nil || diff != gbp(264) {
+		t.Errorf("expected difference of: £2.64, got difference of: %v, err: %v", diff, err)
+	}
+	if neg := gbp(20).Neg(); neg != gbp(-20) {
+		t.Errorf("expected negation of: -£0.20, got negation of: %v", neg)
+	}
+
+	product, err := gbp(284).Mul(3)
+	if err != nil || product != gbp(852) {
+		t.Errorf("expected product of: £8.52, got product of: %v, err: %v", product, err)
+	}
+
+	// check arithmetic at the int64 boundary returns ErrOverflow
+	if _, err := gbp(math.MaxInt64).Add(gbp(1)); !errors.Is(err, checkout.ErrOverflow) {
+		t.Errorf("expected overflow error adding, got err: %v", err)
+	}
+	if _, err := gbp(math.MinInt64).Sub(gbp(1)); !errors.Is(err, checkout.ErrOverflow) {
+		t.Errorf("expected overflow error subtracting, got err: %v", err)
+	}
+	if _, err := gbp(math.MaxInt64 / 2).Mul(3); !errors.Is(err, checkout.ErrOverflow) {
+		t.Errorf("expected overflow error multiplying, got err: %v", err)
+	}
+	if _, err := gbp(math.MinInt64).Mul(-1); !errors.Is(err, checkout.ErrOverflow) {
+		t.Errorf("expected overflow error multiplying, got err: %v", err)
+	}
+	if sum, err := gbp(math.MaxInt64 - 1).Add(gbp(1)); err != nil || sum != gbp(math.MaxInt64) {
+		t.Errorf("expected sum of max int64, got sum of: %v, err: %v", sum, err)
+	}
+
+	usd := checkout.Money{Amount: 20, Currency: "USD"}
+	if _, err := gbp(284).Add(usd); !errors.Is(err, checkout.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch error adding, got err: %v", err)
+	}
+	if _, err := gbp(284).Sub(usd); !errors.Is(err, checkout.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch error subtracting, got err: %v", err)
+	}
+}
+
+// Test_ParseMoney tests parsing decimal amounts in major units into minor units of a currency.
+func Test_ParseMoney(t *testing.T) {
+	testCases := []struct {
+		name     string
+		amount   string
+		currency string
+		expected int64
+		expErr   bool
+	}{
+		{"1: pounds and pence", "12.99", "GBP", 1299, false},
+		{"2: pence only", "0.05", "GBP", 5, false},
+		{"3: single decimal place", "0.5", "GBP", 50, false},
+		{"4: no decimal places", "12", "GBP", 1200, false},
+		{"5: no major units", ".5", "GBP", 50, false},
+		{"6: negative", "-1.10", "EUR", -110, false},
+		{"7: explicit positive sign", "+1.10", "EUR", 110, false},
+		{"8: trailing zeros beyond the exponent", "2.8400", "GBP", 284, false},
+		{"9: zero exponent", "1200", "JPY", 1200, false},
+		{"10: three digit exponent", "1.25", "KWD", 1250, false},
+		{"11: not exactly representable as a float", "0.29", "GBP", 29, false},
+		{"12: max int64", "92233720368547758.07", "GBP", math.MaxInt64, false},
+		{"13: min int64", "-92233720368547758.08", "GBP", math.MinInt64, false},
+		{"14: too large", "92233720368547758.08", "GBP", 0, true},
+		{"15: more decimal places than the currency", "0.505", "GBP", 0, true},
+		{"16: decimal places for zero exponent currency", "1200.5", "JPY", 0, true},
+		{"17: unsupported currency", "1.00", "XYZ", 0, true},
+		{"18: empty", "", "GBP", 0, true},
+		{"19: decimal point only", "-.", "GBP", 0, true},
+		{"20: exponent notation", "1e2", "GBP", 0, true},
+		{"21: thousands separator", "1,000.00", "GBP", 0, true},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			money, err := checkout.ParseMoney(testCase.amount, testCase.currency)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %v", testCase.expErr, err)
+			}
+			if err == nil && money != (checkout.Money{Amount: testCase.expected, Currency: testCase.currency}) {
+				t.Errorf("expected money of: %d %s, got money of: %d %s", testCase.expected, testCase.currency, money.Amount, money.Currency)
+			}
+		})
+	}
+}
+
+// Test_CurrencyExponent tests the CurrencyExponent function for supported and unsupported currencies.
+func Test_CurrencyExponent(t *testing.T) {
+	testCases := []struct {
+		code     string
+		expected int
+		expOk    bool
+	}{
+		{"GBP", 2, true},
+		{"JPY", 0, true},
+		{"KWD", 3, true},
+		{"XYZ", 0, false},
+		{"", 0, false},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		exponent, ok := checkout.CurrencyExponent(testCase.code)
+		if exponent != testCase.expected || ok != testCase.expOk {
+			t.Errorf("%q: expected exponent: %d, ok: %v, got exponent: %d, ok: %v", testCase.code, testCase.expected, testCase.expOk, exponent, ok)
+		}
+	}
+}
+
+// Test_PriceCheckoutCurrency tests pricing checkouts in a non default currency, and checks mixing currencies in a basket returns ErrCurrencyMismatch.
+func Test_PriceCheckoutCurrency(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 500, Currency: "JPY"},
+		"B": {Price: 300, Currency: "JPY"},
+		"C": {Price: 50},
+		"D": {Price: 50, Currency: "XYZ"},
+	}
+
+	receipt, err := checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 1}}, products, checkout.PricingOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got err: %s", err)
+	}
+	if expected := (checkout.Money{Amount: 1300, Currency: "JPY"}); receipt.Total != expected {
+		t.Errorf("expected checkout price of: %v, got checkout price of: %v", expected, receipt.Total)
+	}
+	if s := receipt.Total.String(); s != "¥1300" {
+		t.Errorf("expected formatted checkout price of: ¥1300, got: %s", s)
+	}
+
+	if _, err := checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "C", Quantity: 1}}, products, checkout.PricingOptions{}); !errors.Is(err, checkout.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch error, got err: %v", err)
+	}
+	if _, err := checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "D", Quantity: 1}}, products, checkout.PricingOptions{}); err == nil {
+		t.Errorf("expected unsupported currency error, got nil")
+	}
+}
@@ -0,0 +1,199 @@
+package checkout
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+type (
+	// ScanDecoder reads checkout lines one at a time from newline delimited JSON (NDJSON), with one JSON object per line,
+	// being a CheckoutLine or a scan event from a scanner (e.g. {"code": "A", "till": 4}), so a live scan log can be priced
+	// with a Pricer as it is written. Other fields of scan events are ignored, and blank lines are skipped.
+	//
+	// A scan event with no quantity or measure is a single item (a Quantity of 1), so the scans of a product sold by weight
+	// or volume must each give a measure. Pricing rejects a counted line of a measured product at the position of its first scan,
+	// and DecodeCheckoutNDJSON rejects a later scan of a product code counting an item where earlier scans gave a measure,
+	// or the reverse, at the position of that scan.
+	ScanDecoder struct {
+		file   string
+		reader *bufio.Reader
+		offset int64
+		line   int
+		pos    Position
+		err    error
+	}
+)
+
+// NewScanDecoder returns a ScanDecoder reading NDJSON from r. The Position of errors has no File.
+func NewScanDecoder(r io.Reader) *ScanDecoder {
+	return newScanDecoder("", r)
+}
+
+// newScanDecoder returns a ScanDecoder reading NDJSON from r, named file.
+func newScanDecoder(file string, r io.Reader) *ScanDecoder {
+	return &ScanDecoder{file: file, reader: bufio.NewReader(r)}
+}
+
+// Next returns the checkout line of the next scan, or io.EOF once every line has been read.
+//
+// A line which is not a JSON object, or has a value of the wrong type, is returned as a *SyntaxError located in the line,
+// after which Next returns the same error.
+func (d *ScanDecoder) Next() (CheckoutLine, error) {
+	if d.err != nil {
+		return CheckoutLine{}, d.err
+	}
+
+	cL, err := d.next()
+	if err != nil {
+		d.err = err
+		return CheckoutLine{}, err
+	}
+
+	return cL, nil
+}
+
+// Position returns the Position of the scan last returned by Next.
+func (d *ScanDecoder) Position() Position {
+	return d.pos
+}
+
+// next reads the next line which is not blank, and decodes its scan.
+func (d *ScanDecoder) next() (CheckoutLine, error) {
+
+	for {
+		data, err := d.reader.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return CheckoutLine{}, err
+		}
+		if err != nil && err != io.EOF {
+			return CheckoutLine{}, err
+		}
+
+		start := d.offset
+		d.offset += int64(len(data))
+		d.line++
+
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) == 0 {
+			continue
+		}
+
+		// locate errors within the line, at the offset of the line in the data
+		base := start + int64(bytes.Index(data, trimmed))
+		at := func(offset int64) Position {
+			return Position{File: d.file, Offset: offset, Line: d.line, Column: int(offset-start) + 1}
+		}
+
+		if trimmed[0] != '{' {
+			return CheckoutLine{}, &SyntaxError{Pos: at(base), Err: errors.New("each line of NDJSON checkout data must be a JSON object")}
+		}
+		var cL CheckoutLine
+		if err := json.Unmarshal(trimmed, &cL); err != nil {
+			return CheckoutLine{}, jsonSyntaxError(at, base+int64(len(trimmed)), base, base, err)
+		}
+
+		// a scan with no quantity or measure is a single item
+		var given struct {
+			Quantity json.RawMessage
+			Measure  json.RawMessage
+		}
+		json.Unmarshal(trimmed, &given)
+		if given.Quantity == nil && given.Measure == nil {
+			cL.Quantity = 1
+		}
+
+		d.pos = at(base)
+		return cL, nil
+	}
+}
+
+// DecodeCheckoutNDJSON takes a filePath and returns a slice of instances of CheckoutLine read from NDJSON,
+// with one CheckoutLine or scan event per line (see ScanDecoder).
+//
+// Scans of the same product code are aggregated into one checkout line, in the order the codes were first scanned,
+// with a negative quantity voiding earlier scans of the code.
+// An error is returned if the file cannot be read, a *SyntaxError if a line cannot be decoded,
+// a *LineError located at the scan if a product code is both counted and measured by its scans,
+// or an *OverflowError if the aggregated quantity or measure of a product code overflows.
+func DecodeCheckoutNDJSON(filePath string) ([]CheckoutLine, error) {
+
+	// open file to read from
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+	defer file.Close()
+
+	cLSlice, _, err := readCheckoutNDJSON(filePath, file)
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+
+	return cLSlice, nil
+}
+
+// DecodeCheckoutNDJSONReader reads NDJSON from r, as DecodeCheckoutNDJSON does for a file. The Position of errors has no File.
+func DecodeCheckoutNDJSONReader(r io.Reader) ([]CheckoutLine, error) {
+
+	cLSlice, _, err := readCheckoutNDJSON("", r)
+	if err != nil {
+		return []CheckoutLine{}, err
+	}
+
+	return cLSlice, nil
+}
+
+// readCheckoutNDJSON reads NDJSON from r, named name, returning the aggregated checkout line of each product code,
+// and the Position of the first scan of each.
+func readCheckoutNDJSON(name string, r io.Reader) ([]CheckoutLine, []Position, error) {
+
+	dec := newScanDecoder(name, r)
+	cLSlice := []CheckoutLine{}
+	positions := []Position{}
+	lines := map[string]int{}
+
+	for {
+		cL, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		i, ok := lines[cL.Code]
+		if !ok {
+			lines[cL.Code] = len(cLSlice)
+			cLSlice = append(cLSlice, cL)
+			positions = append(positions, dec.Position())
+			continue
+		}
+
+		// a product is either counted or measured, so its scans cannot mix quantities and measures
+		if cL.Measure != 0 && cLSlice[i].Quantity != 0 {
+			err := errors.New("product is counted by earlier scans, scan must give a quantity rather than a measure")
+			return nil, nil, &LineError{Pos: dec.Position(), Index: i, Code: cL.Code, Err: err}
+		}
+		if cL.Quantity != 0 && cLSlice[i].Measure != 0 {
+			err := errors.New("product is measured by earlier scans, scan must give a measure rather than a quantity")
+			return nil, nil, &LineError{Pos: dec.Position(), Index: i, Code: cL.Code, Err: err}
+		}
+
+		// add the scan to the checkout line of its product code
+		var c checked
+		quantity := c.add(int64(cLSlice[i].Quantity), int64(cL.Quantity))
+		measure := c.add(int64(cLSlice[i].Measure), int64(cL.Measure))
+		if c.overflow || int64(int(quantity)) != quantity {
+			return nil, nil, &OverflowError{Pos: dec.Position(), Line: i, Code: cL.Code, Amount: "scanned quantity"}
+		}
+		cLSlice[i].Quantity = int(quantity)
+		cLSlice[i].Measure = Measure(measure)
+	}
+
+	return cLSlice, positions, nil
+}
@@ -0,0 +1,127 @@
+package checkout_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_DecodeCheckoutNDJSON tests reading scans from NDJSON, aggregating the scans of each product code.
+func Test_DecodeCheckoutNDJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected []checkout.CheckoutLine
+		expErr   string
+	}{
+		{
+			"1: scan events with and without quantities",
+			"{\"code\": \"A\"}\n{\"code\": \"B\", \"quantity\": 2}\n{\"code\": \"A\", \"till\": 4}\n",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 2}},
+			"",
+		},
+		{
+			"2: measured scans, blank lines and a void",
+			"{\"code\": \"F\", \"measure\": 0.5}\r\n\n{\"code\": \"A\"}\n{\"code\": \"F\", \"measure\": \"0.75\"}\n{\"code\": \"A\", \"quantity\": -1}",
+			[]checkout.CheckoutLine{{Code: "F", Measure: 1250}, {Code: "A", Quantity: 0}},
+			"",
+		},
+		{
+			"3: no scans",
+			"\n\n",
+			[]checkout.CheckoutLine{},
+			"",
+		},
+		{
+			"4: line which is not an object",
+			"{\"code\": \"A\"}\n[{\"code\": \"B\"}]\n",
+			nil,
+			"2:1: each line of NDJSON checkout data must be a JSON object",
+		},
+		{
+			"5: two objects on a line",
+			"{\"code\": \"A\"} {\"code\": \"B\"}\n",
+			nil,
+			"1:15: invalid character '{' after top-level value",
+		},
+		{
+			"6: quantity of the wrong type",
+			"{\"code\": \"A\"}\n  {\"code\": \"B\", \"quantity\": \"two\"}\n",
+			nil,
+			"2:3: json: cannot unmarshal string",
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeCheckoutNDJSONReader(strings.NewReader(testCase.data))
+			if testCase.expErr != "" {
+				var syntaxErr *checkout.SyntaxError
+				if !errors.As(err, &syntaxErr) || !strings.HasPrefix(checkout.FormatError(err), testCase.expErr) {
+					t.Errorf("expected *SyntaxError: %s, got err: %s", testCase.expErr, checkout.FormatError(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got err: %v", err)
+			}
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected: %v, got %v", testCase.expected, result)
+			}
+		})
+	}
+
+	// check a scan counting a measured product is rejected at its own line, rather than when the aggregated line is priced
+	_, err := checkout.DecodeCheckoutNDJSONReader(strings.NewReader("{\"code\": \"F\", \"measure\": 0.5}\n{\"code\": \"A\"}\n  {\"code\": \"F\"}\n"))
+	var lineErr *checkout.LineError
+	if !errors.As(err, &lineErr) || lineErr.Index != 0 || lineErr.Pos.Line != 3 || lineErr.Pos.Column != 3 {
+		t.Errorf("expected *LineError for line 0 at 3:3, got err: %s", checkout.FormatError(err))
+	}
+	_, err = checkout.DecodeCheckoutNDJSONReader(strings.NewReader("{\"code\": \"A\"}\n{\"code\": \"A\", \"measure\": 1}\n"))
+	if s := checkout.FormatError(err); s != `2:1: checkout line 0 (product "A"): product is counted by earlier scans, scan must give a quantity rather than a measure` {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+}
+
+// Test_ProcessCheckoutNDJSON tests NDJSON checkout files are selected by their extension, with errors located at the first scan of a product code.
+func Test_ProcessCheckoutNDJSON(t *testing.T) {
+	total, err := checkout.ProcessCheckout("../testdata/checkout_sets/17.ndjson", "../testdata/product_sets/1.json")
+	if err != nil || total != 284 {
+		t.Errorf("expected checkout price of: 284, got checkout price of: %d, err: %v", total, err)
+	}
+
+	_, err = checkout.DecodeCheckoutData("../testdata/checkout_sets/18.ndjson")
+	if s := checkout.FormatError(err); !strings.HasPrefix(s, "../testdata/checkout_sets/18.ndjson:3:3: json: cannot unmarshal string") {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+
+	_, err = checkout.ProcessCheckout("../testdata/checkout_sets/19.ndjson", "../testdata/product_sets/1.json")
+	if s := checkout.FormatError(err); s != `../testdata/checkout_sets/19.ndjson:2:1: checkout line 1 (product "E"): no product code or product code not found in products map` {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+
+	// check scans can be priced one at a time as they are read
+	dec := checkout.NewScanDecoder(strings.NewReader("{\"code\": \"A\"}\n{\"code\": \"A\"}\n{\"code\": \"A\"}\n"))
+	pricer := checkout.NewPricer(map[string]checkout.Product{"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140}}, checkout.PricingOptions{})
+	for {
+		cL, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got err: %v", err)
+		}
+		if err := pricer.Add(cL); err != nil {
+			t.Fatalf("expected no error, got err: %v", err)
+		}
+	}
+	if receipt, err := pricer.Receipt(); err != nil || receipt.Total != gbp(140) {
+		t.Errorf("expected checkout price of: 140, got checkout price of: %v, err: %v", receipt.Total, err)
+	}
+}
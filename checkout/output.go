@@ -0,0 +1,259 @@
+package checkout
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// OutputFormat is the format a priced checkout is written in by WriteReceipt.
+type OutputFormat string
+
+// Output formats, text being the summary written by the CLI by default, json the itemized ReceiptDocument,
+// csv a row for each line, promotion, tax and total of the receipt, and table the same rows aligned in columns.
+const (
+	OutputText  OutputFormat = "text"
+	OutputJSON  OutputFormat = "json"
+	OutputCSV   OutputFormat = "csv"
+	OutputTable OutputFormat = "table"
+)
+
+// ReceiptSchemaVersion is the version of the JSON schema of ReceiptDocument.
+// It is incremented whenever a field is renamed, removed or changes meaning, adding fields does not change it.
+const ReceiptSchemaVersion = 1
+
+type (
+	// ReceiptDocument is the JSON document written by WriteReceipt in the json OutputFormat.
+	//
+	// Every amount is an integer number of minor units (e.g. pence) of Currency, with MinorUnits being the number of minor unit digits
+	// of the currency (e.g. 2 for GBP), so amounts are exact. Measures and tax rates are decimal numbers (e.g. 0.45 kg, a rate of 17.5).
+	// Lists are empty rather than null when there is nothing to list.
+	ReceiptDocument struct {
+		SchemaVersion    int                 `json:"schema_version"`
+		CheckoutFile     string              `json:"checkout_file"`
+		ProductsFile     string              `json:"products_file"`
+		Currency         string              `json:"currency"`
+		MinorUnits       int                 `json:"minor_units"`
+		Lines            []LineDocument      `json:"lines"`
+		RejectedLines    []int               `json:"rejected_lines"`
+		Promotions       []PromotionDocument `json:"promotions"`
+		BasketPromotions []PromotionDocument `json:"basket_promotions"`
+		Taxes            []TaxDocument       `json:"taxes"`
+		Totals           TotalsDocument      `json:"totals"`
+	}
+
+	// LineDocument is a ReceiptLine of a ReceiptDocument, Measure being omitted for products sold by quantity.
+	LineDocument struct {
+		Code      string      `json:"code"`
+		Quantity  int         `json:"quantity"`
+		Measure   json.Number `json:"measure,omitempty"`
+		UnitPrice int64       `json:"unit_price"`
+		Gross     int64       `json:"gross"`
+	}
+
+	// PromotionDocument is an Adjustment of a ReceiptDocument, Amount being negative for a saving.
+	PromotionDocument struct {
+		Rule         string         `json:"rule"`
+		Description  string         `json:"description"`
+		Applications int            `json:"applications"`
+		Claimed      map[string]int `json:"claimed"`
+		Amount       int64          `json:"amount"`
+	}
+
+	// TaxDocument is a TaxTotal of a ReceiptDocument, Rate being a percentage.
+	TaxDocument struct {
+		Class   string      `json:"class"`
+		Rate    json.Number `json:"rate"`
+		Taxable int64       `json:"taxable"`
+		Tax     int64       `json:"tax"`
+	}
+
+	// TotalsDocument holds the totals of a ReceiptDocument, as returned by the Gross and Savings methods and the fields of Receipt.
+	TotalsDocument struct {
+		Gross    int64 `json:"gross"`
+		Savings  int64 `json:"savings"`
+		Subtotal int64 `json:"subtotal"`
+		Tax      int64 `json:"tax"`
+		Total    int64 `json:"total"`
+	}
+)
+
+// checkOutputFormat returns an error if format is not a known OutputFormat.
+func checkOutputFormat(format OutputFormat) error {
+	switch format {
+	case OutputText, OutputJSON, OutputCSV, OutputTable:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, must be text, json, csv or table", format)
+}
+
+// NewReceiptDocument returns the ReceiptDocument of a receipt, priced from the named checkout and products files.
+//
+// An *OverflowError is returned if the gross price or savings of the receipt are too large to be represented.
+func NewReceiptDocument(checkoutName string, productsName string, receipt Receipt) (ReceiptDocument, error) {
+
+	currency := receipt.Total.Currency
+	minorUnits, _ := CurrencyExponent(currency)
+
+	gross, err := receipt.Gross()
+	if err != nil {
+		return ReceiptDocument{}, err
+	}
+	savings, err := receipt.Savings()
+	if err != nil {
+		return ReceiptDocument{}, err
+	}
+
+	doc := ReceiptDocument{
+		SchemaVersion:    ReceiptSchemaVersion,
+		CheckoutFile:     checkoutName,
+		ProductsFile:     productsName,
+		Currency:         currency,
+		MinorUnits:       minorUnits,
+		Lines:            []LineDocument{},
+		RejectedLines:    []int{},
+		Promotions:       promotionDocuments(receipt.Adjustments),
+		BasketPromotions: promotionDocuments(receipt.BasketAdjustments),
+		Taxes:            []TaxDocument{},
+		Totals: TotalsDocument{
+			Gross:    gross.Amount,
+			Savings:  savings.Amount,
+			Subtotal: receipt.Subtotal.Amount,
+			Tax:      receipt.Tax.Amount,
+			Total:    receipt.Total.Amount,
+		},
+	}
+
+	for _, line := range receipt.Lines {
+		lineDoc := LineDocument{Code: line.Code, Quantity: line.Quantity, UnitPrice: line.UnitPrice.Amount, Gross: line.Gross.Amount}
+		if line.Measure != 0 {
+			lineDoc.Measure = json.Number(line.Measure.String())
+		}
+		doc.Lines = append(doc.Lines, lineDoc)
+	}
+	doc.RejectedLines = append(doc.RejectedLines, receipt.Rejected...)
+	for _, tax := range receipt.Taxes {
+		doc.Taxes = append(doc.Taxes, TaxDocument{
+			Class:   tax.Class,
+			Rate:    json.Number(strings.TrimSuffix(tax.Rate.String(), "%")),
+			Taxable: tax.Taxable.Amount,
+			Tax:     tax.Tax.Amount,
+		})
+	}
+
+	return doc, nil
+}
+
+// promotionDocuments returns the PromotionDocument of each adjustment.
+func promotionDocuments(adjustments []Adjustment) []PromotionDocument {
+	docs := []PromotionDocument{}
+	for _, adj := range adjustments {
+		claimed := adj.Claimed
+		if claimed == nil {
+			claimed = map[string]int{}
+		}
+		docs = append(docs, PromotionDocument{
+			Rule:         adj.Rule,
+			Description:  adj.Description,
+			Applications: adj.Applications,
+			Claimed:      claimed,
+			Amount:       adj.Amount.Amount,
+		})
+	}
+	return docs
+}
+
+// WriteReceipt writes a receipt, priced from the named checkout and products files, to out in the given OutputFormat:
+//
+//   - text writes the checkout and products files, the total, the tax of each tax class and any rejected lines, one per line.
+//   - json writes the ReceiptDocument of the receipt (see ReceiptSchemaVersion).
+//   - csv writes a header row, then a row for each line, promotion, basket promotion, rejected line, tax and total of the receipt,
+//     with amounts as decimal numbers in the major units of the currency (see Money.Decimal).
+//   - table writes the rows of csv aligned in columns, with amounts formatted with their currency symbol.
+//
+// An error is returned if the format is unknown, an amount of the receipt overflows, or the receipt cannot be written.
+func WriteReceipt(out io.Writer, format OutputFormat, checkoutName string, productsName string, receipt Receipt) error {
+
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+
+	switch format {
+	case OutputJSON:
+		doc, err := NewReceiptDocument(checkoutName, productsName, receipt)
+		if err != nil {
+			return err
+		}
+		// file names such as <stdin> are written as they are, rather than with < and > escaped for HTML
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case OutputCSV:
+		w := csv.NewWriter(out)
+		w.WriteAll(receiptRows(receipt, Money.Decimal))
+		return w.Error()
+	case OutputTable:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, row := range receiptRows(receipt, Money.String) {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	}
+
+	if _, err := fmt.Fprintf(out, "checkout file: %s\nproducts file: %s\ntotal value of checkout: %v\n", checkoutName, productsName, receipt.Total.String()); err != nil {
+		return err
+	}
+	for _, tax := range receipt.Taxes {
+		if _, err := fmt.Fprintf(out, "%s tax at %v: %v\n", tax.Class, tax.Rate, tax.Tax); err != nil {
+			return err
+		}
+	}
+	if len(receipt.Rejected) > 0 {
+		rejected := make([]string, len(receipt.Rejected))
+		for i, line := range receipt.Rejected {
+			rejected[i] = strconv.Itoa(line)
+		}
+		if _, err := fmt.Fprintf(out, "rejected checkout lines: %s\n", strings.Join(rejected, ", ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// receiptRows returns the header row and the rows of the csv and table output formats of a receipt, with amounts formatted by amount.
+// Each row has a type (line, promotion, basket promotion, rejected, tax or a total), a product code (or tax class), a description,
+// the quantity (or measure), the unit price and the amount.
+func receiptRows(receipt Receipt, amount func(Money) string) [][]string {
+
+	rows := [][]string{{"type", "code", "description", "quantity", "unit_price", "amount"}}
+
+	for _, line := range receipt.Lines {
+		quantity := strconv.Itoa(line.Quantity)
+		if line.Measure != 0 {
+			quantity = line.Measure.String()
+		}
+		rows = append(rows, []string{"line", line.Code, "", quantity, amount(line.UnitPrice), amount(line.Gross)})
+	}
+	for _, adj := range receipt.Adjustments {
+		rows = append(rows, []string{"promotion", "", adj.Description, strconv.Itoa(adj.Applications), "", amount(adj.Amount)})
+	}
+	rows = append(rows, []string{"subtotal", "", "", "", "", amount(receipt.Subtotal)})
+	for _, adj := range receipt.BasketAdjustments {
+		rows = append(rows, []string{"basket_promotion", "", adj.Description, strconv.Itoa(adj.Applications), "", amount(adj.Amount)})
+	}
+	for _, line := range receipt.Rejected {
+		rows = append(rows, []string{"rejected", "", fmt.Sprintf("checkout line %d", line), "", "", ""})
+	}
+	for _, tax := range receipt.Taxes {
+		rows = append(rows, []string{"tax", tax.Class, fmt.Sprintf("%v of %s", tax.Rate, amount(tax.Taxable)), "", "", amount(tax.Tax)})
+	}
+	rows = append(rows, []string{"total", "", "", "", "", amount(receipt.Total)})
+
+	return rows
+}
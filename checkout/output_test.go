@@ -0,0 +1,124 @@
+package checkout_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_WriteReceiptJSON tests the JSON document of a receipt holds its lines, promotions, taxes and totals in minor units,
+// with empty lists written as [] rather than null, and file names written without HTML escaping.
+func Test_WriteReceiptJSON(t *testing.T) {
+	receipt, err := checkout.ProcessCheckoutReceipt("../testdata/checkout_sets/9.json", "../testdata/product_sets/15.json", checkout.PricingOptions{
+		Tax: &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: 1750}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := checkout.WriteReceipt(out, checkout.OutputJSON, checkout.StdinName, "products.json", receipt); err != nil {
+		t.Fatalf("expected no error, got err: %v", err)
+	}
+
+	// check the schema version, and that empty lists are not null
+	if !strings.Contains(out.String(), `"schema_version": 1,`) || !strings.Contains(out.String(), `"basket_promotions": []`) {
+		t.Errorf("expected schema version 1 and empty basket promotions, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), `"checkout_file": "<stdin>",`) {
+		t.Errorf("expected checkout file <stdin> without HTML escaping, got:\n%s", out)
+	}
+
+	var doc checkout.ReceiptDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("expected a JSON document, got err: %v", err)
+	}
+	if expected, err := checkout.NewReceiptDocument(checkout.StdinName, "products.json", receipt); err != nil || !reflect.DeepEqual(doc, expected) {
+		t.Errorf("expected document: %+v, got document: %+v", expected, doc)
+	}
+
+	expLines := []checkout.LineDocument{
+		{Code: "A", Quantity: 3, UnitPrice: 50, Gross: 150},
+		{Code: "F", Measure: "1.25", UnitPrice: 240, Gross: 300},
+		{Code: "G", Measure: "0.333", UnitPrice: 110, Gross: 37},
+	}
+	if !reflect.DeepEqual(doc.Lines, expLines) {
+		t.Errorf("expected lines: %+v, got lines: %+v", expLines, doc.Lines)
+	}
+	if len(doc.Promotions) != 1 || doc.Promotions[0].Amount != -10 || !reflect.DeepEqual(doc.Promotions[0].Claimed, map[string]int{"A": 3}) {
+		t.Errorf("expected one promotion of -10 claiming 3 A, got promotions: %+v", doc.Promotions)
+	}
+	if doc.Currency != "GBP" || doc.MinorUnits != 2 || doc.Totals.Gross != 487 || doc.Totals.Savings != 10 || doc.Totals.Total != receipt.Total.Amount {
+		t.Errorf("expected GBP totals of gross 487, savings 10 and total %d, got: %s %+v", receipt.Total.Amount, doc.Currency, doc.Totals)
+	}
+	for _, tax := range doc.Taxes {
+		if tax.Class == checkout.TaxClassStandard && tax.Rate != "17.5" {
+			t.Errorf("expected standard rate of 17.5, got rate: %s", tax.Rate)
+		}
+	}
+}
+
+// Test_WriteReceipt tests writing a receipt in the text, csv and table output formats, and that an unknown format returns an error.
+func Test_WriteReceipt(t *testing.T) {
+	receipt, err := checkout.ProcessCheckoutReceipt("../testdata/checkout_sets/14.json", "../testdata/product_sets/1.json", checkout.PricingOptions{CollectErrors: true})
+	if err == nil {
+		t.Fatal("expected rejected checkout lines, got no err")
+	}
+
+	testCases := []struct {
+		name     string
+		format   checkout.OutputFormat
+		expected string
+		expErr   bool
+	}{
+		{
+			"1: text",
+			checkout.OutputText,
+			"checkout file: checkout.json\nproducts file: products.json\ntotal value of checkout: £2.00\nrejected checkout lines: 1, 3\n",
+			false,
+		},
+		{
+			"2: csv",
+			checkout.OutputCSV,
+			"type,code,description,quantity,unit_price,amount\n" +
+				"line,A,,3,0.50,1.50\nline,B,,2,0.35,0.70\n" +
+				"promotion,,3 A for £1.40,1,,-0.10\npromotion,,2 B for £0.60,1,,-0.10\n" +
+				"subtotal,,,,,2.00\nrejected,,checkout line 1,,,\nrejected,,checkout line 3,,,\ntotal,,,,,2.00\n",
+			false,
+		},
+		{
+			"3: table",
+			checkout.OutputTable,
+			"type       code  description      quantity  unit_price  amount\n" +
+				"line       A                      3         £0.50       £1.50\n" +
+				"line       B                      2         £0.35       £0.70\n" +
+				"promotion        3 A for £1.40    1                     -£0.10\n" +
+				"promotion        2 B for £0.60    1                     -£0.10\n" +
+				"subtotal                                                £2.00\n" +
+				"rejected         checkout line 1                        \n" +
+				"rejected         checkout line 3                        \n" +
+				"total                                                   £2.00\n",
+			false,
+		},
+		{"4: unknown format", checkout.OutputFormat("xml"), "", true},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			err := checkout.WriteReceipt(out, testCase.format, "checkout.json", "products.json", receipt)
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %v", testCase.expErr, err)
+			}
+			if outStr := out.String(); outStr != testCase.expected {
+				t.Errorf("expected:\n%sgot:\n%s", testCase.expected, outStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package checkout
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrOverflow is returned (wrapped) when an amount of money or a quantity is too large to be represented as an int64.
+var ErrOverflow = errors.New("integer overflow")
+
+// OverflowError is returned when an amount calculated while pricing a checkout overflows.
+//
+// Line is the index of the checkout line the amount was calculated for (the first line of its product code when lines are merged),
+// or -1 for an amount calculated from a Receipt (e.g. its Gross), Code is the product code of that line, if any,
+// and Amount describes what was being calculated (e.g. "line total").
+// Pos is the location of the line in its checkout file, if it was decoded from one by ProcessCheckoutReceipt.
+// OverflowError wraps ErrOverflow, so it can be checked for with errors.Is.
+type OverflowError struct {
+	Pos    Position
+	Line   int
+	Code   string
+	Amount string
+}
+
+func (e *OverflowError) Error() string {
+	switch {
+	case e.Line < 0 && e.Code == "":
+		return fmt.Sprintf("%s overflows", e.Amount)
+	case e.Line < 0:
+		return fmt.Sprintf("product %q: %s overflows", e.Code, e.Amount)
+	}
+	return fmt.Sprintf("checkout line %d (product %q): %s overflows", e.Line, e.Code, e.Amount)
+}
+
+func (e *OverflowError) Unwrap() error {
+	return ErrOverflow
+}
+
+// checked performs int64 arithmetic, recording whether or not any operation has overflowed.
+// Once an operation overflows the results of later operations are meaningless, so overflow is checked once a calculation is complete.
+type checked struct {
+	overflow bool
+}
+
+// add returns a + b.
+func (c *checked) add(a int64, b int64) int64 {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		c.overflow = true
+	}
+	return a + b
+}
+
+// sub returns a - b.
+func (c *checked) sub(a int64, b int64) int64 {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		c.overflow = true
+	}
+	return a - b
+}
+
+// mul returns a * b.
+func (c *checked) mul(a int64, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	product := a * b
+	if product/b != a || (product < 0) != ((a < 0) != (b < 0)) {
+		c.overflow = true
+	}
+	return product
+}
+
+// percentOf returns percent of a non-negative amount, rounded to the nearest whole unit with halves rounded up.
+//
+// The amount is split into hundreds and a remainder, so percent (at most 100) of any int64 amount can be taken without overflowing.
+func percentOf(amount int64, percent int) int64 {
+	return amount/100*int64(percent) + (amount%100*int64(percent)+50)/100
+}
@@ -0,0 +1,166 @@
+package checkout_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_PriceCheckoutOverflow tests pricing checkouts with amounts at the int64 boundary,
+// checking an *OverflowError naming the offending checkout line is returned when an amount overflows.
+func Test_PriceCheckoutOverflow(t *testing.T) {
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		products      map[string]checkout.Product
+		expected      int64
+		expErr        *checkout.OverflowError
+	}{
+		{
+			"1: line total at max int64",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+			map[string]checkout.Product{"A": {Price: math.MaxInt64}},
+			math.MaxInt64,
+			nil,
+		},
+		{
+			"2: line total overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 2}},
+			map[string]checkout.Product{"A": {Price: math.MaxInt64}},
+			0,
+			&checkout.OverflowError{Line: 0, Code: "A", Amount: "line total"},
+		},
+		{
+			"3: line total at min int64",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+			map[string]checkout.Product{"A": {Price: math.MinInt64}},
+			math.MinInt64,
+			nil,
+		},
+		{
+			"4: negative line total overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "B", Quantity: 3}},
+			map[string]checkout.Product{"A": {Price: 1}, "B": {Price: math.MinInt64 / 2}},
+			0,
+			&checkout.OverflowError{Line: 1, Code: "B", Amount: "line total"},
+		},
+		{
+			"5: subtotal overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "C", Quantity: 5}, {Code: "B", Quantity: 1}},
+			map[string]checkout.Product{"A": {Price: math.MaxInt64}, "B": {Price: 1}, "C": {Price: 0}},
+			0,
+			&checkout.OverflowError{Line: 2, Code: "B", Amount: "subtotal"},
+		},
+		{
+			"6: quantity of merged lines overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: math.MaxInt64}, {Code: "A", Quantity: 1}},
+			map[string]checkout.Product{"A": {Price: 0}},
+			0,
+			&checkout.OverflowError{Line: 1, Code: "A", Amount: "line total"},
+		},
+		{
+			"7: price of merged lines overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "B", Quantity: 1}, {Code: "A", Quantity: 1}},
+			map[string]checkout.Product{"A": {Price: math.MaxInt64}, "B": {Price: math.MinInt64}},
+			0,
+			&checkout.OverflowError{Line: 2, Code: "A", Amount: "line total"},
+		},
+		{
+			"8: multibuy adjustment overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 2}},
+			map[string]checkout.Product{"A": {Price: 1, Rules: []checkout.RuleSpec{rule("multibuy", `{"Quantity": 1, "Price": 9223372036854775807}`)}}},
+			0,
+			&checkout.OverflowError{Line: 0, Code: "A", Amount: "multibuy adjustment"},
+		},
+		{
+			"9: bundle adjustment overflows",
+			[]checkout.CheckoutLine{{Code: "B", Quantity: 1}, {Code: "A", Quantity: 2}},
+			map[string]checkout.Product{
+				"A": {Price: 1, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"A": 1, "B": 1}, "Price": -9223372036854775807}`)}},
+				"B": {Price: 1},
+			},
+			0,
+			&checkout.OverflowError{Line: 1, Code: "A", Amount: "bundle adjustment"},
+		},
+		{
+			"10: tiered price overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 10}},
+			map[string]checkout.Product{"A": {Price: 1, Tiers: []checkout.Tier{{MinQuantity: 2, Price: math.MaxInt64}}}},
+			0,
+			&checkout.OverflowError{Line: 0, Code: "A", Amount: "tiered price"},
+		},
+		{
+			"11: group multibuy adjustment overflows",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 2}},
+			map[string]checkout.Product{
+				"A": {Price: 2, Groups: []string{"drinks"}, Rules: []checkout.RuleSpec{rule("groupmultibuy", `{"Group": "drinks", "Quantity": 1, "Price": -9223372036854775807}`)}},
+				"B": {Price: 1, Groups: []string{"drinks"}},
+			},
+			0,
+			&checkout.OverflowError{Line: 0, Code: "A", Amount: "groupmultibuy adjustment"},
+		},
+		{
+			"12: percent off max int64 rounded without overflowing",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+			map[string]checkout.Product{"A": {Price: math.MaxInt64, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 50}`)}}},
+			4611686018427387903,
+			nil,
+		},
+		{
+			"13: quantity of group items overflows",
+			[]checkout.CheckoutLine{{Code: "C", Quantity: 1}, {Code: "A", Quantity: math.MaxInt64}, {Code: "B", Quantity: math.MaxInt64}},
+			map[string]checkout.Product{
+				"A": {Price: 1, Groups: []string{"drinks"}, Rules: []checkout.RuleSpec{rule("groupcheapestfree", `{"Group": "drinks", "Quantity": 3}`)}},
+				"B": {Price: 1, Groups: []string{"drinks"}},
+				"C": {Price: -math.MaxInt64},
+			},
+			0,
+			&checkout.OverflowError{Line: 2, Code: "B", Amount: "group quantity"},
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			receipt, err := checkout.PriceCheckout(testCase.checkoutLines, testCase.products, checkout.PricingOptions{})
+
+			if testCase.expErr == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got err: %s", err)
+				}
+				if receipt.Total.Amount != testCase.expected {
+					t.Errorf("expected checkout price of: %d, got checkout price of: %d", testCase.expected, receipt.Total.Amount)
+				}
+				return
+			}
+
+			// check the error is an OverflowError naming the expected line
+			var overflowErr *checkout.OverflowError
+			if !errors.As(err, &overflowErr) {
+				t.Fatalf("expected overflow error, got err: %v", err)
+			}
+			if *overflowErr != *testCase.expErr {
+				t.Errorf("expected overflow error: %v, got overflow error: %v", testCase.expErr, overflowErr)
+			}
+			if !errors.Is(err, checkout.ErrOverflow) {
+				t.Errorf("expected error to wrap ErrOverflow, got err: %v", err)
+			}
+		})
+	}
+}
+
+// Test_OptimalAllocationOverflow checks overflowing adjustments are reported when using optimal allocation.
+func Test_OptimalAllocationOverflow(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 1, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"A": 1}, "Price": -4611686018427387904}`)}},
+	}
+
+	_, err := checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "A", Quantity: 2}}, products, checkout.PricingOptions{Allocation: checkout.AllocationOptimal})
+	var overflowErr *checkout.OverflowError
+	if !errors.As(err, &overflowErr) || overflowErr.Line != 0 || overflowErr.Code != "A" {
+		t.Errorf("expected overflow error for checkout line 0, got err: %v", err)
+	}
+}
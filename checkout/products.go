@@ -4,7 +4,9 @@ Package checkout provides utilities to calculate a total checkout price from JSO
 package checkout
 
 import (
-	"errors"
+	"io"
+	"io/fs"
+	"os"
 )
 
 type (
@@ -12,24 +14,75 @@ type (
 	//
 	// Contains a product code (e.g. "A") represented as a string, and an integer Quantity of the item (e.g. 5). A negative Quantity is invalid
 	//
+	// Products sold by weight or volume are given a Measure of their Unit (e.g. 1.25 for 1.25kg) rather than a Quantity. A negative Measure is invalid
+	//
 	// DecodeCheckoutData (io.go) returns data from checkout JSON as a slice of CheckoutLine
 	CheckoutLine struct {
 		Code     string
 		Quantity int
+		Measure  Measure
 	}
 
 	// Product stores price information about a particular product.
 	//
 	// Price is an integer value (e.g. 6), acting as the unit cost, negative prices are allowed to allow discounting functionality.
+	// Prices are in the minor units of the Currency, in JSON they may also be given in major units as decimals (e.g. "0.50", see UnmarshalJSON in io.go).
 	// OfferQuantity is the quantity which must be purchased to benefit from the offer,
 	// with OfferPrice being the price of the given OfferQuantity (e.g. if OfferQuantity is 3, and OfferPrice is 150, 3 items will cost 150).
 	// If OfferQuantity is 0/ not given, offers will be ignored. A negative OfferQuantity is invalid
 	//
+	// Rules references further pricing rules by their type name, applied in order after the offer (see RuleSpec/ RegisterRule in rules.go).
+	//
+	// Groups tags the product with the names of groups it belongs to (e.g. "drinks"), used by group deals.
+	//
+	// Currency is the ISO 4217 currency code the prices of the product are in minor units of (e.g. "GBP", with a Price of 50 being £0.50),
+	// if not given DefaultCurrency is used.
+	//
+	// TaxClass is the tax class of the product, one of TaxClassStandard (the default), TaxClassReduced, TaxClassZero or TaxClassExempt,
+	// taxed at the rate of the class in the TaxTable of PricingOptions (see tax.go).
+	//
+	// Unit is the unit of measure the product is sold and priced by, one of UnitEach (the default), UnitKilogram, UnitGram or UnitLitre,
+	// with Price being the price of one Unit (see measure.go). Products sold by weight or volume cannot have offers, tiers or pricing rules.
+	//
+	// Tiers is an optional tiered pricing table, giving a lower unit price as more items are bought (see Tier),
+	// with TierMode being either TierModeVolume (the default) or TierModeGraduated. Tiers cannot be used with OfferQuantity.
+	//
 	// DecodePriceData (io.go) returns a map of [string: Product Code]Product
 	Product struct {
 		Price         int
 		OfferQuantity int
 		OfferPrice    int
+		Rules         []RuleSpec
+		Groups        []string
+		Tiers         []Tier
+		TierMode      string
+		Currency      string
+		TaxClass      string
+		Unit          string
+	}
+
+	// Tier is a band of a tiered pricing table, pricing items at Price once at least MinQuantity items are bought.
+	//
+	// Items bought below the MinQuantity of the first tier are priced at the normal Price of the product
+	// (e.g. a Price of 50 with tiers of {10, 45} and {50, 40} prices 1-9 items at 50, 10-49 items at 45, and 50+ items at 40).
+	Tier struct {
+		MinQuantity int
+		Price       int
+	}
+
+	// PricingOptions configures how a checkout is priced by PriceCheckout.
+	//
+	// Allocation selects how pricing rules are allocated when offers overlap, if not given AllocationGreedy is used.
+	// BasketRules references the basket rules applied in order to the whole basket, once its pricing rules have been applied
+	// (see BasketRule/ RegisterBasketRule in basketrules.go).
+	// Tax is the TaxTable used to calculate the tax on the checkout, if nil no tax is calculated.
+	// If CollectErrors is true, checkout lines which cannot be priced are rejected rather than failing the checkout,
+	// with the rest of the checkout priced and the errors of every rejected line returned together (see LineErrors).
+	PricingOptions struct {
+		Allocation    AllocationMode
+		BasketRules   []RuleSpec
+		Tax           *TaxTable
+		CollectErrors bool
 	}
 )
 
@@ -37,81 +90,306 @@ type (
 //
 // It accepts the path to the checkout json file, and the path to the products list json file.
 //
-// Returned is the total value from GetCheckoutPrice and any errors that have occured calling other functions.
+// Returned is the total value from ProcessCheckoutReceipt using the default PricingOptions, in the minor units of its currency,
+// and any errors that have occured calling other functions.
 func ProcessCheckout(checkoutPath string, productsPath string) (int, error) {
 
-	// get checkout line arr and products map
-	checkoutLines, err := DecodeCheckoutData(checkoutPath)
+	receipt, err := ProcessCheckoutReceipt(checkoutPath, productsPath, PricingOptions{})
 	if err != nil {
 		return 0, err
 	}
-	products, err := DecodeProductData(productsPath)
+
+	return receipt.intTotal()
+}
+
+// ProcessCheckoutReader is ProcessCheckout reading the checkout and products JSON from readers rather than files.
+func ProcessCheckoutReader(checkout io.Reader, products io.Reader) (int, error) {
+
+	receipt, err := ProcessCheckoutReceiptReader(checkout, products, PricingOptions{})
 	if err != nil {
 		return 0, err
 	}
 
-	checkoutPrice, err := GetCheckoutPrice(checkoutLines, products)
+	return receipt.intTotal()
+}
+
+// ProcessCheckoutReceipt is a function for pricing a checkout from JSON data files.
+//
+// It accepts the path to the checkout json file, the path to the products list json file, and the PricingOptions to use.
+//
+// Returned is the Receipt from PriceCheckout and any errors that have occured calling other functions.
+// A *LineError or *OverflowError naming a checkout line has the Position of the line in the checkout file set,
+// as do the errors of a *LineErrors, which is returned with the partial Receipt if opts.CollectErrors is true.
+func ProcessCheckoutReceipt(checkoutPath string, productsPath string, opts PricingOptions) (Receipt, error) {
+	return processCheckoutFiles(checkoutPath, productsPath, "", opts)
+}
+
+// processCheckoutFiles prices a checkout from the checkout and products files, reading the products in the given Format,
+// or the Format of their extension if format is "".
+func processCheckoutFiles(checkoutPath string, productsPath string, format Format, opts PricingOptions) (Receipt, error) {
+
+	// open checkout and products files to read from
+	checkoutFile, err := os.Open(checkoutPath)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer checkoutFile.Close()
+
+	productsFile, err := os.Open(productsPath)
 	if err != nil {
-		return 0, err
+		return Receipt{}, err
 	}
+	defer productsFile.Close()
 
-	return checkoutPrice, nil
+	return processCheckout(checkoutPath, checkoutFile, productsPath, productsFile, format, opts)
+}
+
+// ProcessCheckoutReceiptReader is ProcessCheckoutReceipt reading the checkout and products JSON from readers rather than files,
+// so a checkout can be priced from HTTP bodies, stdin or in-memory buffers. Positions of errors have no File.
+func ProcessCheckoutReceiptReader(checkout io.Reader, products io.Reader, opts PricingOptions) (Receipt, error) {
+	return processCheckout("", checkout, "", products, FormatJSON, opts)
+}
+
+// ProcessCheckoutReceiptFS is ProcessCheckoutReceipt reading the named checkout and products JSON files from fsys (e.g. an embed.FS).
+func ProcessCheckoutReceiptFS(fsys fs.FS, checkoutName string, productsName string, opts PricingOptions) (Receipt, error) {
+
+	// open checkout and products files to read from
+	checkoutFile, err := fsys.Open(checkoutName)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer checkoutFile.Close()
+
+	productsFile, err := fsys.Open(productsName)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer productsFile.Close()
+
+	return processCheckout(checkoutName, checkoutFile, productsName, productsFile, "", opts)
+}
+
+// processCheckout prices a checkout from the checkout JSON read from checkout and the product catalog read from products,
+// in the given Format or the Format of its name if format is "", using their names to locate errors.
+func processCheckout(checkoutName string, checkout io.Reader, productsName string, products io.Reader, format Format, opts PricingOptions) (Receipt, error) {
+
+	// get checkout line arr, with the position of each line, and products map
+	checkoutLines, positions, err := readCheckout(checkoutName, checkout)
+	if err != nil {
+		return Receipt{}, err
+	}
+	prodMap, err := readProductsFormat(productsName, products, format)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	receipt, err := PriceCheckout(checkoutLines, prodMap, opts)
+	if err != nil {
+		// locate the checkout lines of the error in the checkout file, returning the partial receipt of CollectErrors
+		return receipt, locate(err, positions)
+	}
+
+	return receipt, nil
+}
+
+// currency returns the currency code of the product, or DefaultCurrency if not given.
+func (p Product) currency() string {
+	if p.Currency == "" {
+		return DefaultCurrency
+	}
+	return p.Currency
+}
+
+// UnitPrice returns the Price of the product as Money in the currency of the product.
+func (p Product) UnitPrice() Money {
+	return Money{Amount: int64(p.Price), Currency: p.currency()}
 }
 
 // GetCheckoutLinePrice is a method for CheckoutLine which also accepts a map representing product prices,
 // this map uses productCode as the key, and a Product as the value.
 //
+// The line is priced on its own as a single line checkout with GetCheckoutPrice.
+//
 // returns an error if the checkout line quantity is negative, or if the offer quantity is negative
 func (cL CheckoutLine) GetCheckoutLinePrice(products map[string]Product) (int, error) {
+	return GetCheckoutPrice([]CheckoutLine{cL}, products)
+}
 
-	lineTotal := 0
+// GetCheckoutPrice accepts a slice of CheckoutLine and a map of representing product prices, this map uses productCode as the key, and a Product as the value.
+//
+// Checkout lines with the same product code are merged before pricing, so offers apply across them,
+// e.g. lines of 2 A and 1 A are priced as 3 A and get a 3 for 140 offer on A. Earlier versions priced each line on its own.
+//
+// Returned is the Total of the Receipt from GetCheckoutReceipt in the minor units of its currency, and any error which occured pricing the checkout.
+func GetCheckoutPrice(cLSlice []CheckoutLine, products map[string]Product) (int, error) {
+
+	receipt, err := GetCheckoutReceipt(cLSlice, products)
+	if err != nil {
+		return 0, err
+	}
+
+	return receipt.intTotal()
+}
+
+// GetCheckoutPriceCollectErrors is GetCheckoutPrice with the CollectErrors option of PricingOptions,
+// every checkout line which can be priced is, with the lines which cannot being rejected.
+//
+// Returned is the Total of the lines which were priced, and a *LineErrors holding the error of each rejected line.
+// Any other error is returned with a Total of 0.
+func GetCheckoutPriceCollectErrors(cLSlice []CheckoutLine, products map[string]Product) (int, error) {
+
+	receipt, err := PriceCheckout(cLSlice, products, PricingOptions{CollectErrors: true})
+
+	total, intErr := receipt.intTotal()
+	if intErr != nil {
+		return 0, intErr
+	}
+	return total, err
+}
+
+// GetCheckoutReceipt accepts a slice of CheckoutLine and a map of representing product prices, this map uses productCode as the key, and a Product as the value.
+//
+// Returned is the Receipt from PriceCheckout using the default PricingOptions.
+func GetCheckoutReceipt(cLSlice []CheckoutLine, products map[string]Product) (Receipt, error) {
+	return PriceCheckout(cLSlice, products, PricingOptions{})
+}
+
+// PriceCheckout accepts a slice of CheckoutLine, a map of representing product prices, and the PricingOptions to use.
+// The products map uses productCode as the key, and a Product as the value.
+//
+// Each checkout line is priced at the normal Price of its product, and the lines are merged into a Basket,
+// the pricing rules of the products in the basket are then allocated using opts.Allocation, with their adjustments added to give the Subtotal.
+// Then opts.BasketRules are applied in order, with their adjustments added to the Subtotal to give the Total.
+// Finally if opts.Tax is given, the tax of each tax class is calculated, and added to the Total if prices are tax exclusive.
+//
+// If an error occurs creating the Basket or applying a pricing rule, it is returned from this function.
+// If an amount overflows an int64, the error is an *OverflowError naming the checkout line it was calculated for.
+//
+// If opts.CollectErrors is true, the lines which cannot be added to the Basket, or whose product has pricing rules which cannot be built,
+// are rejected, and the other lines priced,
+// with the partial Receipt returned marking the Rejected lines, along with a *LineErrors holding the error of each.
+func PriceCheckout(cLSlice []CheckoutLine, products map[string]Product, opts PricingOptions) (Receipt, error) {
+
+	var basket *Basket
+	var lineErrs *LineErrors
+	var err error
+
+	if opts.CollectErrors {
+		basket, lineErrs = collectBasket(cLSlice, products)
+	} else if basket, err = NewBasket(cLSlice, products); err != nil {
+		return Receipt{}, err
+	}
+
+	rejected := map[int]bool{}
+	if lineErrs != nil {
+		for _, line := range lineErrs.Lines() {
+			rejected[line] = true
+		}
+	}
 
-	// check for invalid checkout quantity.
-	if cL.Quantity < 0 {
-		return 0, errors.New("checkout line quantity cannot be negative")
+	receipt := Receipt{
+		Lines:    make([]ReceiptLine, 0, len(cLSlice)),
+		Subtotal: basket.money(0),
+		Tax:      basket.money(0),
 	}
 
-	// check prod key in products map, if not return err else continue
-	if prod, ok := products[cL.Code]; ok {
-		// check for invalid offer quantity
-		if prod.OfferQuantity < 0 {
-			return 0, errors.New("offer quantity cannot be negative")
+	// loop over checkout lines, adding their normal price to the subtotal
+	for i, cL := range cLSlice {
+		if rejected[i] {
+			continue
+		}
+		prod, _ := basket.Product(cL.Code)
+		amount, ok := lineGross(cL, prod)
+		if !ok {
+			return Receipt{}, &OverflowError{Line: i, Code: cL.Code, Amount: "line total"}
 		}
-		// check if there is an offer to be used
-		if prod.OfferQuantity > 0 {
-			// offer exists, apply offer for as many items as possible, and normal price for the rest
-			lineTotal += (cL.Quantity / prod.OfferQuantity) * prod.OfferPrice
-			lineTotal += (cL.Quantity % prod.OfferQuantity) * prod.Price
-		} else {
-			// no offer, apply normal price for all items
-			lineTotal += cL.Quantity * prod.Price
+		gross := basket.money(amount)
+		receipt.Lines = append(receipt.Lines, ReceiptLine{Code: cL.Code, Quantity: cL.Quantity, Measure: cL.Measure, UnitPrice: prod.UnitPrice(), Gross: gross})
+		if receipt.Subtotal, err = receipt.Subtotal.Add(gross); err != nil {
+			return Receipt{}, &OverflowError{Line: i, Code: cL.Code, Amount: "subtotal"}
 		}
-	} else {
-		return 0, errors.New("no product code or product code not found in products map")
 	}
 
-	return lineTotal, nil
+	if receipt, err = priceBasket(basket, receipt, opts); err != nil {
+		return Receipt{}, err
+	}
+
+	if lineErrs != nil {
+		receipt.Rejected = lineErrs.Lines()
+		return receipt, lineErrs
+	}
+
+	return receipt, nil
 }
 
-// GetCheckoutPrice accepts a slice of CheckoutLine and a map of representing product prices, this map uses productCode as the key, and a Product as the value.
-//
-// Checkout lines are iterated over, for each the CheckoutLine method is called, and the lineTotal is added to the sum.
+// priceBasket applies the pricing rules, basket rules and tax of opts to a basket,
+// given a receipt holding the gross price of its lines and their Subtotal.
+func priceBasket(basket *Basket, receipt Receipt, opts PricingOptions) (Receipt, error) {
+
+	var err error
+
+	receipt.Adjustments, err = applyPricingRules(basket, opts.Allocation)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	// add adjustments made by pricing rules to the subtotal
+	for _, adj := range receipt.Adjustments {
+		if receipt.Subtotal, err = receipt.Subtotal.Add(adj.Amount); err != nil {
+			return Receipt{}, basket.overflowError(basket.firstClaimed(adj), "subtotal")
+		}
+	}
+	receipt.Total = receipt.Subtotal
+
+	receipt.BasketAdjustments, receipt.Total, err = applyBasketRules(basket, receipt, opts.BasketRules)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	if opts.Tax != nil {
+		receipt.Taxes, receipt.Tax, err = applyTax(basket, receipt, *opts.Tax)
+		if err != nil {
+			return Receipt{}, err
+		}
+		if !opts.Tax.Inclusive {
+			if receipt.Total, err = receipt.Total.Add(receipt.Tax); err != nil {
+				return Receipt{}, basket.overflowError(basket.codes[len(basket.codes)-1], "total")
+			}
+		}
+	}
+
+	return receipt, nil
+}
+
+// basketRules returns the pricing rules of each product in the basket, in the order the products appear in the basket.
 //
-// If an error occurs in GetCheckoutLinePrice, it is returned from this function.
-func GetCheckoutPrice(cLSlice []CheckoutLine, products map[string]Product) (int, error) {
+// Rules evaluated across the whole basket (e.g. group deals) are returned after the rules of each product,
+// with a rule listed by more than one product in the basket only being returned once.
+func basketRules(basket *Basket) ([]PricingRule, error) {
 
-	checkoutTotal := 0
+	productRules := []PricingRule{}
+	basketLevelRules := []PricingRule{}
+	seen := map[string]bool{}
 
-	// loop over checkout lines, get their price and add it to the total checkout price
-	for _, cL := range cLSlice {
+	for _, code := range basket.Codes() {
+		prod, _ := basket.Product(code)
 
-		cLPrice, err := cL.GetCheckoutLinePrice(products)
+		rules, err := prod.PricingRules(code)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 
-		checkoutTotal += cLPrice
+		for _, rule := range rules {
+			if bRule, ok := rule.(basketLevelRule); ok {
+				if !seen[bRule.key()] {
+					seen[bRule.key()] = true
+					basketLevelRules = append(basketLevelRules, rule)
+				}
+				continue
+			}
+			productRules = append(productRules, rule)
+		}
 	}
 
-	return checkoutTotal, nil
+	return append(productRules, basketLevelRules...), nil
 }
@@ -1,7 +1,13 @@
 package checkout_test
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/billiem/checkout-system/checkout"
 )
@@ -20,7 +26,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 	}{
 		{
 			"1: example data prod 1",
-			checkout.CheckoutLine{"A", 3},
+			checkout.CheckoutLine{Code: "A", Quantity: 3},
 			map[string]checkout.Product{"A": {
 				Price: 50, OfferQuantity: 3, OfferPrice: 140}},
 			140,
@@ -28,7 +34,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"2: example data prod 2",
-			checkout.CheckoutLine{"B", 3},
+			checkout.CheckoutLine{Code: "B", Quantity: 3},
 			map[string]checkout.Product{"B": {
 				Price: 35, OfferQuantity: 2, OfferPrice: 60,
 			}},
@@ -37,7 +43,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"3: example data prod 3",
-			checkout.CheckoutLine{"C", 1},
+			checkout.CheckoutLine{Code: "C", Quantity: 1},
 			map[string]checkout.Product{"C": {
 				Price: 25,
 			}},
@@ -46,7 +52,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"4: example data prod 4",
-			checkout.CheckoutLine{"D", 2},
+			checkout.CheckoutLine{Code: "D", Quantity: 2},
 			map[string]checkout.Product{"D": {
 				Price: 12,
 			}},
@@ -55,7 +61,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"5: negative checkout line quantity",
-			checkout.CheckoutLine{"D", -3},
+			checkout.CheckoutLine{Code: "D", Quantity: -3},
 			map[string]checkout.Product{
 				"D": {
 					Price: 10,
@@ -66,7 +72,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"6: negative offer line quantity",
-			checkout.CheckoutLine{"D", 1},
+			checkout.CheckoutLine{Code: "D", Quantity: 1},
 			map[string]checkout.Product{
 				"D": {
 					Price: 15, OfferQuantity: -15, OfferPrice: 10,
@@ -77,7 +83,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"7: checkout line product code not in products list",
-			checkout.CheckoutLine{"A", 3},
+			checkout.CheckoutLine{Code: "A", Quantity: 3},
 			map[string]checkout.Product{
 				"B": {
 					Price: 10,
@@ -99,7 +105,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"9: negative price positive offer price",
-			checkout.CheckoutLine{"A", 14},
+			checkout.CheckoutLine{Code: "A", Quantity: 14},
 			map[string]checkout.Product{
 				"A": {
 					Price: -44, OfferQuantity: 4, OfferPrice: 19,
@@ -110,7 +116,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"10: positive price negative offer price",
-			checkout.CheckoutLine{"C", 25},
+			checkout.CheckoutLine{Code: "C", Quantity: 25},
 			map[string]checkout.Product{
 				"C": {
 					Price: 12, OfferQuantity: 3, OfferPrice: -2,
@@ -121,7 +127,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"11: negative price and negative offer price",
-			checkout.CheckoutLine{"D", 66},
+			checkout.CheckoutLine{Code: "D", Quantity: 66},
 			map[string]checkout.Product{
 				"D": {
 					Price: -4, OfferQuantity: 3, OfferPrice: -5,
@@ -132,7 +138,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"12: large checkout line quantity",
-			checkout.CheckoutLine{"F", 29124908},
+			checkout.CheckoutLine{Code: "F", Quantity: 29124908},
 			map[string]checkout.Product{
 				"F": {
 					Price: 4, OfferQuantity: 44, OfferPrice: 140,
@@ -143,7 +149,7 @@ func Test_GetCheckoutLinePrice(t *testing.T) {
 		},
 		{
 			"13: unused offer",
-			checkout.CheckoutLine{"D", 4},
+			checkout.CheckoutLine{Code: "D", Quantity: 4},
 			map[string]checkout.Product{
 				"D": {
 					Price: 12, OfferQuantity: 100, OfferPrice: 4,
@@ -186,10 +192,10 @@ func Test_GetCheckoutPrice(t *testing.T) {
 		{
 			"1: given example checkout data & given example products list",
 			[]checkout.CheckoutLine{
-				{"A", 3},
-				{"B", 3},
-				{"C", 1},
-				{"D", 2},
+				{Code: "A", Quantity: 3},
+				{Code: "B", Quantity: 3},
+				{Code: "C", Quantity: 1},
+				{Code: "D", Quantity: 2},
 			},
 			map[string]checkout.Product{
 				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
@@ -203,10 +209,10 @@ func Test_GetCheckoutPrice(t *testing.T) {
 		{
 			"2: normal checkout data & given example products list",
 			[]checkout.CheckoutLine{
-				{"A", 66},
-				{"B", 3123},
-				{"C", 661},
-				{"D", 21},
+				{Code: "A", Quantity: 66},
+				{Code: "B", Quantity: 3123},
+				{Code: "C", Quantity: 661},
+				{Code: "D", Quantity: 21},
 			},
 			map[string]checkout.Product{
 				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
@@ -220,10 +226,10 @@ func Test_GetCheckoutPrice(t *testing.T) {
 		{
 			"3: negative checkout quantity line & given example products list",
 			[]checkout.CheckoutLine{
-				{"A", 66},
-				{"B", 3123},
-				{"C", 661},
-				{"D", -1},
+				{Code: "A", Quantity: 66},
+				{Code: "B", Quantity: 3123},
+				{Code: "C", Quantity: 661},
+				{Code: "D", Quantity: -1},
 			},
 			map[string]checkout.Product{
 				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
@@ -244,10 +250,10 @@ func Test_GetCheckoutPrice(t *testing.T) {
 		{
 			"5: products map missing a product",
 			[]checkout.CheckoutLine{
-				{"A", 66},
-				{"B", 3123},
-				{"C", 661},
-				{"D", 4},
+				{Code: "A", Quantity: 66},
+				{Code: "B", Quantity: 3123},
+				{Code: "C", Quantity: 661},
+				{Code: "D", Quantity: 4},
 			},
 			map[string]checkout.Product{
 				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
@@ -260,10 +266,10 @@ func Test_GetCheckoutPrice(t *testing.T) {
 		{
 			"6: no offers",
 			[]checkout.CheckoutLine{
-				{"A", 12},
-				{"B", 4},
-				{"C", 2},
-				{"D", 1},
+				{Code: "A", Quantity: 12},
+				{Code: "B", Quantity: 4},
+				{Code: "C", Quantity: 2},
+				{Code: "D", Quantity: 1},
 			},
 			map[string]checkout.Product{
 				"A": {Price: 50},
@@ -277,9 +283,9 @@ func Test_GetCheckoutPrice(t *testing.T) {
 		{
 			"7: all products have offers",
 			[]checkout.CheckoutLine{
-				{"A", 12},
-				{"B", 4},
-				{"C", 22},
+				{Code: "A", Quantity: 12},
+				{Code: "B", Quantity: 4},
+				{Code: "C", Quantity: 22},
 			},
 			map[string]checkout.Product{
 				"A": {Price: 50, OfferQuantity: 4, OfferPrice: 150},
@@ -292,9 +298,9 @@ func Test_GetCheckoutPrice(t *testing.T) {
 		{
 			"8: negative prices and negative offer prices",
 			[]checkout.CheckoutLine{
-				{"A", 12},
-				{"B", 4},
-				{"C", 15},
+				{Code: "A", Quantity: 12},
+				{Code: "B", Quantity: 4},
+				{Code: "C", Quantity: 15},
 			},
 			map[string]checkout.Product{
 				"A": {Price: -50},
@@ -304,6 +310,20 @@ func Test_GetCheckoutPrice(t *testing.T) {
 			-700,
 			false,
 		},
+		{
+			"9: repeated product codes priced together",
+			[]checkout.CheckoutLine{
+				{Code: "A", Quantity: 2},
+				{Code: "B", Quantity: 1},
+				{Code: "A", Quantity: 1},
+			},
+			map[string]checkout.Product{
+				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+				"B": {Price: 35},
+			},
+			175,
+			false,
+		},
 	}
 
 	// loop over and run test cases
@@ -323,6 +343,56 @@ func Test_GetCheckoutPrice(t *testing.T) {
 	}
 }
 
+// Test_GetCheckoutReceipt tests the GetCheckoutReceipt function.
+//
+// It passes a map of [productCode]Product using bundles and a slice of CheckoutLine to the function,
+// checking the adjustment reported for each bundle, and the receipt total.
+func Test_GetCheckoutReceipt(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50, Rules: []checkout.RuleSpec{
+			{Type: "bundle", Params: json.RawMessage(`{"Name": "meal deal", "Items": {"A": 1, "B": 1, "C": 1}, "Price": 100}`)},
+		}},
+		"B": {Price: 35},
+		"C": {Price: 25, Rules: []checkout.RuleSpec{
+			{Type: "bundle", Params: json.RawMessage(`{"Items": {"C": 1, "D": 2}, "Price": 30}`)},
+		}},
+		"D": {Price: 12},
+	}
+
+	receipt, err := checkout.GetCheckoutReceipt(
+		[]checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 3}, {Code: "C", Quantity: 4}, {Code: "D", Quantity: 5}},
+		products,
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got err: %s", err)
+	}
+
+	expected := []checkout.Adjustment{
+		{
+			Rule:         "bundle",
+			Description:  "meal deal for £1.00",
+			Applications: 2,
+			Claimed:      map[string]int{"A": 2, "B": 2, "C": 2},
+			Amount:       gbp(-20),
+		},
+		{
+			Rule:         "bundle",
+			Description:  "bundle C + D for £0.30",
+			Applications: 2,
+			Claimed:      map[string]int{"C": 2, "D": 4},
+			Amount:       gbp(-38),
+		},
+	}
+
+	// compare adjustments and total to expected vals
+	if !reflect.DeepEqual(receipt.Adjustments, expected) {
+		t.Errorf("expected adjustments: %v, got adjustments: %v", expected, receipt.Adjustments)
+	}
+	if receipt.Total != gbp(307) {
+		t.Errorf("expected receipt total of: 307, got receipt total of: %v", receipt.Total)
+	}
+}
+
 // Test_ProcessCheckout tests the ProcessCheckout function.
 //
 // It passes in a filepath to the checkout json data, and a filepath to the products json data,
@@ -384,6 +454,27 @@ func Test_ProcessCheckout(t *testing.T) {
 			0,
 			true,
 		},
+		{
+			"8: example checkout data with product data using pricing rules",
+			"1.json",
+			"6.json",
+			284,
+			false,
+		},
+		{
+			"9: example checkout data with product data using percent off and amount off",
+			"1.json",
+			"7.json",
+			269,
+			false,
+		},
+		{
+			"10: example checkout data with product data using group deals",
+			"1.json",
+			"8.json",
+			269,
+			false,
+		},
 	}
 
 	// loop over test cases
@@ -404,3 +495,109 @@ func Test_ProcessCheckout(t *testing.T) {
 		})
 	}
 }
+
+// Test_PriceCheckoutCollectErrors tests pricing a checkout with the CollectErrors option,
+// checking the valid lines are priced, and the invalid lines are rejected with their errors returned together.
+func Test_PriceCheckoutCollectErrors(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+		"B": {Price: 35, OfferQuantity: 2, OfferPrice: 60},
+		"C": {Price: math.MaxInt64 / 2},
+		"J": {Price: 500, Currency: "JPY"},
+	}
+	opts := checkout.PricingOptions{CollectErrors: true}
+
+	checkoutLines := []checkout.CheckoutLine{
+		{Code: "A", Quantity: 3},
+		{Code: "E", Quantity: 1},
+		{Code: "B", Quantity: -2},
+		{Code: "B", Quantity: 2},
+		{Code: "J", Quantity: 1},
+		{Code: "C", Quantity: 1},
+		{Code: "C", Quantity: 2},
+	}
+	receipt, err := checkout.PriceCheckout(checkoutLines, products, opts)
+
+	var lineErrs *checkout.LineErrors
+	if !errors.As(err, &lineErrs) {
+		t.Fatalf("expected *LineErrors, got err: %v", err)
+	}
+	if expected := []int{1, 2, 4, 6}; !reflect.DeepEqual(receipt.Rejected, expected) || !reflect.DeepEqual(lineErrs.Lines(), expected) {
+		t.Errorf("expected rejected lines: %v, got rejected lines: %v, errors for lines: %v", expected, receipt.Rejected, lineErrs.Lines())
+	}
+	for _, target := range []error{checkout.ErrUnknownProduct, checkout.ErrNegativeQuantity, checkout.ErrCurrencyMismatch, checkout.ErrOverflow} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected line errors to match: %v, got err: %v", target, err)
+		}
+	}
+	if len(receipt.Lines) != 3 {
+		t.Errorf("expected 3 receipt lines, got %d receipt lines", len(receipt.Lines))
+	}
+	if expected := gbp(140 + 60 + math.MaxInt64/2); receipt.Total != expected {
+		t.Errorf("expected checkout price of: %v, got checkout price of: %v", expected, receipt.Total)
+	}
+
+	// check a checkout with no invalid lines returns no error
+	receipt, err = checkout.PriceCheckout([]checkout.CheckoutLine{{Code: "A", Quantity: 3}}, products, opts)
+	if err != nil || receipt.Rejected != nil || receipt.Total != gbp(140) {
+		t.Errorf("expected checkout price of: 140 with no rejected lines, got checkout price of: %v, rejected lines: %v, err: %v", receipt.Total, receipt.Rejected, err)
+	}
+
+	// check products whose pricing rules cannot be built only reject their own lines
+	invalidRules := map[string]checkout.Product{
+		"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+		"B": {Price: 35, OfferQuantity: -2, OfferPrice: 60},
+		"C": {Price: 25, Rules: []checkout.RuleSpec{rule("fake", `{}`)}},
+	}
+	checkoutLines = []checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 1}, {Code: "C", Quantity: 1}, {Code: "B", Quantity: 1}}
+	receipt, err = checkout.PriceCheckout(checkoutLines, invalidRules, opts)
+	if !errors.As(err, &lineErrs) || !reflect.DeepEqual(receipt.Rejected, []int{1, 2, 3}) || receipt.Total != gbp(140) {
+		t.Errorf("expected checkout price of: 140 with lines 1, 2 and 3 rejected, got checkout price of: %v, rejected lines: %v, err: %v", receipt.Total, receipt.Rejected, err)
+	}
+	var lineErr *checkout.LineError
+	if len(lineErrs.Errors) == 0 || !errors.As(lineErrs.Errors[0], &lineErr) || lineErr.Code != "B" || lineErr.Error() != `checkout line 1 (product "B"): offer quantity cannot be negative` {
+		t.Errorf("expected line error for product B, got err: %v", err)
+	}
+
+	// check the product code is given when the rules are built without collecting errors
+	_, err = checkout.PriceCheckout(checkoutLines, invalidRules, checkout.PricingOptions{})
+	if err == nil || err.Error() != `product "B": offer quantity cannot be negative` {
+		t.Errorf("expected offer quantity error for product B, got err: %v", err)
+	}
+
+	// check GetCheckoutPriceCollectErrors returns the total of the valid lines
+	total, err := checkout.GetCheckoutPriceCollectErrors([]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "E", Quantity: 1}}, products)
+	if !errors.As(err, &lineErrs) || total != 140 {
+		t.Errorf("expected checkout price of: 140 and *LineErrors, got checkout price of: %d, err: %v", total, err)
+	}
+}
+
+// Test_ProcessCheckoutReader tests pricing checkouts read from readers and an fs.FS, rather than files.
+func Test_ProcessCheckoutReader(t *testing.T) {
+
+	checkoutJSON := `[{"code": "A", "quantity": 3}, {"code": "B", "quantity": 3}, {"code": "C", "quantity": 1}, {"code": "D", "quantity": 2}]`
+	productsJSON := `{"A": {"Price": 50, "OfferQuantity": 3, "OfferPrice": 140}, "B": {"Price": 35, "OfferQuantity": 2, "OfferPrice": 60}, "C": {"Price": 25}, "D": {"Price": 12}}`
+
+	total, err := checkout.ProcessCheckoutReader(strings.NewReader(checkoutJSON), strings.NewReader(productsJSON))
+	if err != nil || total != 284 {
+		t.Errorf("expected checkout value of: 284, got checkout value of: %d, err: %v", total, err)
+	}
+
+	// check a line error is located in the checkout JSON, with no file name
+	_, err = checkout.ProcessCheckoutReceiptReader(strings.NewReader("[\n  {\"code\": \"E\", \"quantity\": 1}\n]"), strings.NewReader(productsJSON), checkout.PricingOptions{})
+	if s := checkout.FormatError(err); s != `2:3: checkout line 0 (product "E"): no product code or product code not found in products map` {
+		t.Errorf("unexpected formatted error: %s", s)
+	}
+
+	fsys := fstest.MapFS{
+		"checkout.json": {Data: []byte(checkoutJSON)},
+		"products.json": {Data: []byte(productsJSON)},
+	}
+	receipt, err := checkout.ProcessCheckoutReceiptFS(fsys, "checkout.json", "products.json", checkout.PricingOptions{})
+	if err != nil || receipt.Total != gbp(284) {
+		t.Errorf("expected checkout value of: 284, got checkout value of: %v, err: %v", receipt.Total, err)
+	}
+	if _, err := checkout.ProcessCheckoutReceiptFS(fsys, "checkout.json", "fake.json", checkout.PricingOptions{}); err == nil {
+		t.Errorf("expected error for non-existent products file, got nil")
+	}
+}
@@ -0,0 +1,642 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// multiBuyRule prices each Quantity items of a product at Price (e.g. 3 for 140), with any remaining items left at the normal price.
+//
+// legacy is true for the rule of the OfferQuantity/ OfferPrice of a Product, which AllocationOptimal always applies (see mandatoryRule).
+type multiBuyRule struct {
+	Code     string
+	Quantity int
+	Price    int
+	legacy   bool
+}
+
+// newMultiBuyRule is the RuleFactory for the "multibuy" rule type.
+//
+// Params are the Quantity which must be purchased to benefit from the offer, and the Price of that quantity.
+func newMultiBuyRule(code string, params json.RawMessage) (PricingRule, error) {
+
+	var p struct {
+		Quantity int
+		Price    int
+	}
+	if err := decodeRuleParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	return multiBuyRule{Code: code, Quantity: p.Quantity, Price: p.Price}, nil
+}
+
+// Apply claims Quantity items for each application of the offer, with the saving being the difference between
+// the offer price and the normal price of those items.
+func (r multiBuyRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	prod, ok := basket.Product(r.Code)
+	if !ok {
+		return Adjustment{}, nil
+	}
+
+	n := applications(basket.Remaining(r.Code)/r.Quantity, limit)
+	if n == 0 {
+		return Adjustment{}, nil
+	}
+
+	claimed := n * r.Quantity
+
+	var c checked
+	amount := c.sub(c.mul(int64(n), int64(r.Price)), c.mul(int64(claimed), int64(prod.Price)))
+	if c.overflow {
+		return Adjustment{}, basket.overflowError(r.Code, "multibuy adjustment")
+	}
+
+	if err := basket.Claim(r.Code, claimed); err != nil {
+		return Adjustment{}, err
+	}
+
+	return Adjustment{
+		Rule:         "multibuy",
+		Description:  fmt.Sprintf("%d %s for %s", r.Quantity, r.Code, basket.money(int64(r.Price))),
+		Applications: n,
+		Claimed:      map[string]int{r.Code: claimed},
+		Amount:       basket.money(amount),
+	}, nil
+}
+
+// percentOffRule takes Percent off the price of every remaining item of a product (e.g. 20% off).
+type percentOffRule struct {
+	Code    string
+	Percent int
+}
+
+// newPercentOffRule is the RuleFactory for the "percentoff" rule type.
+//
+// The only param is the Percent to take off the price, which must be between 1 and 100.
+func newPercentOffRule(code string, params json.RawMessage) (PricingRule, error) {
+
+	var p struct {
+		Percent int
+	}
+	if err := decodeRuleParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Percent <= 0 || p.Percent > 100 {
+		return nil, errors.New("percent must be between 1 and 100")
+	}
+
+	return percentOffRule{Code: code, Percent: p.Percent}, nil
+}
+
+// Apply claims one item for each application, with the saving being Percent of the combined price of the claimed items.
+//
+// The saving is rounded to the nearest whole unit, with halves rounded in the customers favour.
+// Products with a price of 0 or less are not discounted.
+func (r percentOffRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	prod, ok := basket.Product(r.Code)
+	if !ok || prod.Price <= 0 {
+		return Adjustment{}, nil
+	}
+
+	n := applications(basket.Remaining(r.Code), limit)
+	if n == 0 {
+		return Adjustment{}, nil
+	}
+	if err := basket.Claim(r.Code, n); err != nil {
+		return Adjustment{}, err
+	}
+
+	return Adjustment{
+		Rule:         "percentoff",
+		Description:  fmt.Sprintf("%d%% off %s", r.Percent, r.Code),
+		Applications: n,
+		Claimed:      map[string]int{r.Code: n},
+		Amount:       basket.money(-percentOf(int64(n)*int64(prod.Price), r.Percent)),
+	}, nil
+}
+
+// amountOffRule takes a fixed Amount off the price of every remaining item of a product (e.g. 5 off).
+type amountOffRule struct {
+	Code   string
+	Amount int
+}
+
+// newAmountOffRule is the RuleFactory for the "amountoff" rule type.
+//
+// The only param is the Amount to take off the price of each item, which must be positive.
+func newAmountOffRule(code string, params json.RawMessage) (PricingRule, error) {
+
+	var p struct {
+		Amount int
+	}
+	if err := decodeRuleParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	return amountOffRule{Code: code, Amount: p.Amount}, nil
+}
+
+// Apply claims one item for each application, with the saving being Amount for each claimed item.
+//
+// The saving for each item cannot be more than its price, so an item cannot be discounted below 0.
+// Products with a price of 0 or less are not discounted.
+func (r amountOffRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	prod, ok := basket.Product(r.Code)
+	if !ok || prod.Price <= 0 {
+		return Adjustment{}, nil
+	}
+
+	n := applications(basket.Remaining(r.Code), limit)
+	if n == 0 {
+		return Adjustment{}, nil
+	}
+	if err := basket.Claim(r.Code, n); err != nil {
+		return Adjustment{}, err
+	}
+
+	saving := r.Amount
+	if saving > prod.Price {
+		saving = prod.Price
+	}
+
+	return Adjustment{
+		Rule:         "amountoff",
+		Description:  fmt.Sprintf("%s off %s", basket.money(int64(r.Amount)), r.Code),
+		Applications: n,
+		Claimed:      map[string]int{r.Code: n},
+		Amount:       basket.money(-int64(n) * int64(saving)),
+	}, nil
+}
+
+// buyXGetYRule gives Get items of GetCode at Percent off for every Buy items of Code which are purchased
+// (e.g. buy 2 get 1 free, or buy 1 get the second half price).
+type buyXGetYRule struct {
+	Code    string
+	Buy     int
+	GetCode string
+	Get     int
+	Percent int
+}
+
+// newBuyXGetYRule is the RuleFactory for the "buyxgety" rule type.
+//
+// Params are the Buy quantity of the product which must be purchased, and the Get quantity which is discounted.
+// GetCode is the product code of the discounted items, if not given the discounted items are the same product.
+// Percent is the discount on the Get items, between 1 and 100, if not given the Get items are free.
+func newBuyXGetYRule(code string, params json.RawMessage) (PricingRule, error) {
+
+	var p struct {
+		Buy     int
+		Get     int
+		GetCode string
+		Percent int
+	}
+	if err := decodeRuleParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Buy <= 0 || p.Get <= 0 {
+		return nil, errors.New("buy and get quantities must be positive")
+	}
+	if p.Percent < 0 || p.Percent > 100 {
+		return nil, errors.New("percent must be between 1 and 100")
+	}
+
+	rule := buyXGetYRule{Code: code, Buy: p.Buy, GetCode: p.GetCode, Get: p.Get, Percent: p.Percent}
+	if rule.GetCode == "" {
+		rule.GetCode = code
+	}
+	if rule.Percent == 0 {
+		rule.Percent = 100
+	}
+
+	return rule, nil
+}
+
+// Apply claims Buy items of Code and Get items of GetCode for each application,
+// with the saving being Percent of the combined price of the claimed Get items, rounded as for percentoff.
+//
+// If GetCode has a price of 0 or less, or is not in the basket, the rule is not applied.
+func (r buyXGetYRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	getProd, ok := basket.Product(r.GetCode)
+	if !ok || getProd.Price <= 0 {
+		return Adjustment{}, nil
+	}
+
+	// number of times the basket allows the rule to be applied
+	var available int
+	if r.GetCode == r.Code {
+		available = basket.Remaining(r.Code) / (r.Buy + r.Get)
+	} else {
+		available = basket.Remaining(r.Code) / r.Buy
+		if getAvailable := basket.Remaining(r.GetCode) / r.Get; getAvailable < available {
+			available = getAvailable
+		}
+	}
+
+	n := applications(available, limit)
+	if n == 0 {
+		return Adjustment{}, nil
+	}
+
+	claimed := map[string]int{}
+	claimed[r.Code] += n * r.Buy
+	claimed[r.GetCode] += n * r.Get
+	for code, quantity := range claimed {
+		if err := basket.Claim(code, quantity); err != nil {
+			return Adjustment{}, err
+		}
+	}
+
+	description := fmt.Sprintf("buy %d %s get %d %s free", r.Buy, r.Code, r.Get, r.GetCode)
+	if r.Percent < 100 {
+		description = fmt.Sprintf("buy %d %s get %d %s %d%% off", r.Buy, r.Code, r.Get, r.GetCode, r.Percent)
+	}
+
+	return Adjustment{
+		Rule:         "buyxgety",
+		Description:  description,
+		Applications: n,
+		Claimed:      claimed,
+		Amount:       basket.money(-percentOf(int64(n*r.Get)*int64(getProd.Price), r.Percent)),
+	}, nil
+}
+
+// bundleRule prices a set of products bought together at a single Price (e.g. A + B + C for 100),
+// using quantities from the checkout lines of each product in the bundle. Code is the product the bundle is listed under.
+type bundleRule struct {
+	Code  string
+	Name  string
+	Items map[string]int
+	Price int
+}
+
+// newBundleRule is the RuleFactory for the "bundle" rule type.
+//
+// Params are the Items in the bundle, mapping each product code to its quantity in the bundle, and the Price of the bundle.
+// An optional Name is used to describe the bundle on the receipt (e.g. "meal deal").
+//
+// As the rules of a product are only applied when it is in the checkout, a bundle must include the product it is listed under.
+func newBundleRule(code string, params json.RawMessage) (PricingRule, error) {
+
+	var p struct {
+		Name  string
+		Items map[string]int
+		Price int
+	}
+	if err := decodeRuleParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.Items[code]; !ok {
+		return nil, fmt.Errorf("bundle must include product %q", code)
+	}
+	for itemCode, quantity := range p.Items {
+		if quantity <= 0 {
+			return nil, fmt.Errorf("bundle quantity of product %q must be positive", itemCode)
+		}
+	}
+
+	rule := bundleRule{Code: code, Name: p.Name, Items: p.Items, Price: p.Price}
+	if rule.Name == "" {
+		codes := make([]string, 0, len(p.Items))
+		for itemCode := range p.Items {
+			codes = append(codes, itemCode)
+		}
+		sort.Strings(codes)
+		rule.Name = "bundle " + strings.Join(codes, " + ")
+	}
+
+	return rule, nil
+}
+
+// Apply claims the Items of the bundle for each application,
+// with the saving being the difference between the bundle Price and the normal price of the items.
+//
+// The rule is applied as many times as the product in the bundle with the fewest remaining items allows,
+// leaving any other items at their normal price. It is only applied while the normal price of the items is more than the bundle Price.
+func (r bundleRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	var c checked
+	available := -1
+	normalPrice := int64(0)
+
+	for code, quantity := range r.Items {
+		prod, ok := basket.Product(code)
+		if !ok {
+			return Adjustment{}, nil
+		}
+		if itemAvailable := basket.Remaining(code) / quantity; available == -1 || itemAvailable < available {
+			available = itemAvailable
+		}
+		normalPrice = c.add(normalPrice, c.mul(int64(quantity), int64(prod.Price)))
+	}
+
+	n := applications(available, limit)
+	if n <= 0 || (!c.overflow && normalPrice <= int64(r.Price)) {
+		return Adjustment{}, nil
+	}
+
+	amount := c.mul(int64(n), c.sub(int64(r.Price), normalPrice))
+	if c.overflow {
+		return Adjustment{}, basket.overflowError(r.Code, "bundle adjustment")
+	}
+
+	claimed := map[string]int{}
+	for code, quantity := range r.Items {
+		claimed[code] = n * quantity
+		if err := basket.Claim(code, claimed[code]); err != nil {
+			return Adjustment{}, err
+		}
+	}
+
+	return Adjustment{
+		Rule:         "bundle",
+		Description:  fmt.Sprintf("%s for %s", r.Name, basket.money(int64(r.Price))),
+		Applications: n,
+		Claimed:      claimed,
+		Amount:       basket.money(amount),
+	}, nil
+}
+
+// groupItem is the remaining quantity of a product in a group, at its unit price
+type groupItem struct {
+	code     string
+	price    int
+	quantity int
+}
+
+// groupItems returns the remaining items in the basket belonging to a group, ordered from the most to least expensive,
+// with products of equal price ordered by product code. Products with a price of 0 or less are not included.
+func groupItems(basket *Basket, group string) []groupItem {
+
+	items := []groupItem{}
+
+	for _, code := range basket.Codes() {
+		prod, _ := basket.Product(code)
+		if prod.Price <= 0 || basket.Remaining(code) == 0 || !prod.inGroup(group) {
+			continue
+		}
+		items = append(items, groupItem{code: code, price: prod.Price, quantity: basket.Remaining(code)})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].price != items[j].price {
+			return items[i].price > items[j].price
+		}
+		return items[i].code < items[j].code
+	})
+
+	return items
+}
+
+// inGroup returns whether or not the product is tagged with a group.
+func (p Product) inGroup(group string) bool {
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// groupChunk is a number of identical chunks of size items taken from ordered group items
+type groupChunk struct {
+	count    int            // number of identical chunks
+	items    map[string]int // quantity of each product code in one chunk
+	price    int64          // normal price of one chunk
+	cheapest int64          // price of the cheapest item in one chunk
+}
+
+// applyGroupChunks splits ordered group items into chunks of size items, passing them in order to take,
+// which returns how many of the chunks to take, stopping at the first chunk which is not taken.
+// Runs of identical chunks (from a single product) are passed together, so large quantities are not split one chunk at a time.
+//
+// At most limit chunks are taken, the taken items are claimed from the basket,
+// with the number of chunks taken and the claimed quantity of each product code returned.
+// An *OverflowError is returned if the total quantity of the items, or the normal price of a chunk, overflows.
+func applyGroupChunks(basket *Basket, items []groupItem, size int, limit int, take func(chunk groupChunk) int) (int, map[string]int, error) {
+
+	var c checked
+	total := int64(0)
+	for _, item := range items {
+		if total = c.add(total, int64(item.quantity)); c.overflow {
+			return 0, nil, basket.overflowError(item.code, "group quantity")
+		}
+	}
+
+	n := 0
+	claimed := map[string]int{}
+	i, offset := 0, 0
+
+	for total-int64(n)*int64(size) >= int64(size) && (limit == NoLimit || n < limit) {
+		var c checked
+		chunk := groupChunk{count: 1, items: map[string]int{}}
+
+		if left := items[i].quantity - offset; left >= size {
+			// chunk is within a single product, so take all identical chunks of that product together
+			chunk.count = applications(left/size, limitLeft(limit, n))
+			chunk.items[items[i].code] = size
+			chunk.price = c.mul(int64(size), int64(items[i].price))
+			chunk.cheapest = int64(items[i].price)
+		} else {
+			// chunk spans more than 1 product
+			for need := size; need > 0; {
+				qty := items[i+len(chunk.items)].quantity
+				if len(chunk.items) == 0 {
+					qty -= offset
+				}
+				if qty > need {
+					qty = need
+				}
+				item := items[i+len(chunk.items)]
+				chunk.items[item.code] = qty
+				chunk.price = c.add(chunk.price, c.mul(int64(qty), int64(item.price)))
+				chunk.cheapest = int64(item.price)
+				need -= qty
+			}
+		}
+
+		if c.overflow {
+			return 0, nil, basket.overflowError(items[i].code, "group price")
+		}
+
+		taken := take(chunk)
+		if taken == 0 {
+			break
+		}
+
+		// advance past the taken items
+		for code, qty := range chunk.items {
+			claimed[code] += taken * qty
+		}
+		for step := taken * size; step > 0; {
+			if left := items[i].quantity - offset; left > step {
+				offset += step
+				step = 0
+			} else {
+				step -= left
+				i, offset = i+1, 0
+			}
+		}
+		n += taken
+
+		if taken < chunk.count {
+			break
+		}
+	}
+
+	for code, qty := range claimed {
+		if err := basket.Claim(code, qty); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return n, claimed, nil
+}
+
+// limitLeft returns how many more applications can be made once n applications have been made, given a limit.
+func limitLeft(limit int, n int) int {
+	if limit == NoLimit {
+		return NoLimit
+	}
+	return limit - n
+}
+
+// groupMultiBuyRule prices any Quantity items from a Group of products at Price (e.g. any 3 drinks for 100).
+type groupMultiBuyRule struct {
+	Group    string
+	Quantity int
+	Price    int
+}
+
+// newGroupMultiBuyRule is the RuleFactory for the "groupmultibuy" rule type.
+//
+// Params are the Group of products, the Quantity which must be purchased from the group, and the Price of that quantity.
+func newGroupMultiBuyRule(code string, params json.RawMessage) (PricingRule, error) {
+
+	var p struct {
+		Group    string
+		Quantity int
+		Price    int
+	}
+	if err := decodeRuleParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Group == "" {
+		return nil, errors.New("group must be given")
+	}
+	if p.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	return groupMultiBuyRule{Group: p.Group, Quantity: p.Quantity, Price: p.Price}, nil
+}
+
+func (r groupMultiBuyRule) key() string {
+	return fmt.Sprintf("groupmultibuy %q %d %d", r.Group, r.Quantity, r.Price)
+}
+
+// Apply claims Quantity items from the group for each application, taking the most expensive items first,
+// with the saving being the difference between the offer price and the normal price of those items.
+//
+// The rule is only applied while the normal price of the items is more than the offer price.
+func (r groupMultiBuyRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	var c checked
+	saving := int64(0)
+	items := groupItems(basket, r.Group)
+	n, claimed, err := applyGroupChunks(basket, items, r.Quantity, limit, func(chunk groupChunk) int {
+		if chunk.price <= int64(r.Price) {
+			return 0
+		}
+		saving = c.add(saving, c.mul(int64(chunk.count), c.sub(chunk.price, int64(r.Price))))
+		return chunk.count
+	})
+	if err != nil || n == 0 {
+		return Adjustment{}, err
+	}
+	if c.overflow {
+		return Adjustment{}, basket.overflowError(items[0].code, "groupmultibuy adjustment")
+	}
+
+	return Adjustment{
+		Rule:         "groupmultibuy",
+		Description:  fmt.Sprintf("any %d %s for %s", r.Quantity, r.Group, basket.money(int64(r.Price))),
+		Applications: n,
+		Claimed:      claimed,
+		Amount:       basket.money(-saving),
+	}, nil
+}
+
+// groupCheapestFreeRule gives the cheapest item free for every Quantity items bought from a Group of products
+// (e.g. cheapest of 3 drinks free).
+type groupCheapestFreeRule struct {
+	Group    string
+	Quantity int
+}
+
+// newGroupCheapestFreeRule is the RuleFactory for the "groupcheapestfree" rule type.
+//
+// Params are the Group of products, and the Quantity which must be purchased from the group to get the cheapest free.
+func newGroupCheapestFreeRule(code string, params json.RawMessage) (PricingRule, error) {
+
+	var p struct {
+		Group    string
+		Quantity int
+	}
+	if err := decodeRuleParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Group == "" {
+		return nil, errors.New("group must be given")
+	}
+	if p.Quantity < 2 {
+		return nil, errors.New("quantity must be at least 2")
+	}
+
+	return groupCheapestFreeRule{Group: p.Group, Quantity: p.Quantity}, nil
+}
+
+func (r groupCheapestFreeRule) key() string {
+	return fmt.Sprintf("groupcheapestfree %q %d", r.Group, r.Quantity)
+}
+
+// Apply claims Quantity items from the group for each application, taking the most expensive items first,
+// with the saving being the price of the cheapest of those items.
+func (r groupCheapestFreeRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	var c checked
+	saving := int64(0)
+	items := groupItems(basket, r.Group)
+	n, claimed, err := applyGroupChunks(basket, items, r.Quantity, limit, func(chunk groupChunk) int {
+		saving = c.add(saving, c.mul(int64(chunk.count), chunk.cheapest))
+		return chunk.count
+	})
+	if err != nil || n == 0 {
+		return Adjustment{}, err
+	}
+	if c.overflow {
+		return Adjustment{}, basket.overflowError(items[0].code, "groupcheapestfree adjustment")
+	}
+
+	return Adjustment{
+		Rule:         "groupcheapestfree",
+		Description:  fmt.Sprintf("cheapest of %d %s free", r.Quantity, r.Group),
+		Applications: n,
+		Claimed:      claimed,
+		Amount:       basket.money(-saving),
+	}, nil
+}
@@ -0,0 +1,315 @@
+package checkout_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// rule returns a RuleSpec for a rule type and its JSON params
+func rule(ruleType string, params string) checkout.RuleSpec {
+	return checkout.RuleSpec{Type: ruleType, Params: json.RawMessage(params)}
+}
+
+// Test_Promotions tests each of the built in promotion rule types through the GetCheckoutPrice function.
+//
+// It passes a map of [productCode]Product using the rule types and a slice of CheckoutLine to the function,
+// checking for the correct expected checkout price, and whether or not an error is expected.
+func Test_Promotions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		products      map[string]checkout.Product
+		expected      int
+		expErr        bool
+	}{
+		{
+			"1: percent off",
+			[]checkout.CheckoutLine{{Code: "C", Quantity: 4}},
+			map[string]checkout.Product{
+				"C": {Price: 25, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 20}`)}},
+			},
+			80,
+			false,
+		},
+		{
+			"2: percent off saving rounded down",
+			[]checkout.CheckoutLine{{Code: "C", Quantity: 3}},
+			map[string]checkout.Product{
+				"C": {Price: 25, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 15}`)}},
+			},
+			64,
+			false,
+		},
+		{
+			"3: percent off saving rounded up",
+			[]checkout.CheckoutLine{{Code: "C", Quantity: 3}},
+			map[string]checkout.Product{
+				"C": {Price: 25, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 33}`)}},
+			},
+			50,
+			false,
+		},
+		{
+			"4: percent off saving of half rounded in customers favour",
+			[]checkout.CheckoutLine{{Code: "C", Quantity: 1}},
+			map[string]checkout.Product{
+				"C": {Price: 25, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 10}`)}},
+			},
+			22,
+			false,
+		},
+		{
+			"5: amount off",
+			[]checkout.CheckoutLine{{Code: "D", Quantity: 3}},
+			map[string]checkout.Product{
+				"D": {Price: 12, Rules: []checkout.RuleSpec{rule("amountoff", `{"Amount": 5}`)}},
+			},
+			21,
+			false,
+		},
+		{
+			"6: amount off more than price",
+			[]checkout.CheckoutLine{{Code: "D", Quantity: 3}},
+			map[string]checkout.Product{
+				"D": {Price: 12, Rules: []checkout.RuleSpec{rule("amountoff", `{"Amount": 20}`)}},
+			},
+			0,
+			false,
+		},
+		{
+			"7: negative price not discounted",
+			[]checkout.CheckoutLine{{Code: "D", Quantity: 3}, {Code: "C", Quantity: 1}},
+			map[string]checkout.Product{
+				"C": {Price: -25, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 10}`)}},
+				"D": {Price: -12, Rules: []checkout.RuleSpec{rule("amountoff", `{"Amount": 5}`)}},
+			},
+			-61,
+			false,
+		},
+		{
+			"8: percent off applied to items left over from multibuy offer",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 7}},
+			map[string]checkout.Product{
+				"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 10}`)}},
+			},
+			325,
+			false,
+		},
+		{
+			"9: percent over 100",
+			[]checkout.CheckoutLine{{Code: "C", Quantity: 1}},
+			map[string]checkout.Product{
+				"C": {Price: 25, Rules: []checkout.RuleSpec{rule("percentoff", `{"Percent": 120}`)}},
+			},
+			0,
+			true,
+		},
+		{
+			"10: negative amount",
+			[]checkout.CheckoutLine{{Code: "D", Quantity: 1}},
+			map[string]checkout.Product{
+				"D": {Price: 12, Rules: []checkout.RuleSpec{rule("amountoff", `{"Amount": -5}`)}},
+			},
+			0,
+			true,
+		},
+		{
+			"11: buy 2 get 1 free",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 7}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("buyxgety", `{"Buy": 2, "Get": 1}`)}},
+			},
+			250,
+			false,
+		},
+		{
+			"12: buy 1 get 1 half price",
+			[]checkout.CheckoutLine{{Code: "C", Quantity: 5}},
+			map[string]checkout.Product{
+				"C": {Price: 25, Rules: []checkout.RuleSpec{rule("buyxgety", `{"Buy": 1, "Get": 1, "Percent": 50}`)}},
+			},
+			100,
+			false,
+		},
+		{
+			"13: buy A get B free",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 2}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("buyxgety", `{"Buy": 1, "Get": 1, "GetCode": "B"}`)}},
+				"B": {Price: 35},
+			},
+			150,
+			false,
+		},
+		{
+			"14: buy A get B free with no B in checkout",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("buyxgety", `{"Buy": 1, "Get": 1, "GetCode": "B"}`)}},
+				"B": {Price: 35},
+			},
+			150,
+			false,
+		},
+		{
+			"15: buy 2 A get 1 B 50% off, with B offer applied first",
+			[]checkout.CheckoutLine{{Code: "B", Quantity: 3}, {Code: "A", Quantity: 4}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("buyxgety", `{"Buy": 2, "Get": 1, "GetCode": "B", "Percent": 50}`)}},
+				"B": {Price: 35, OfferQuantity: 2, OfferPrice: 60},
+			},
+			277,
+			false,
+		},
+		{
+			"16: buy x get y with no get quantity",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("buyxgety", `{"Buy": 2}`)}},
+			},
+			0,
+			true,
+		},
+		{
+			"17: bundle applied as many times as basket allows, leftovers at normal price",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 2}, {Code: "C", Quantity: 5}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"A": 1, "B": 1, "C": 1}, "Price": 100}`)}},
+				"B": {Price: 35},
+				"C": {Price: 25},
+			},
+			325,
+			false,
+		},
+		{
+			"18: bundle with quantities of more than 1",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "C", Quantity: 5}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"A": 1, "C": 2}, "Price": 80}`)}},
+				"C": {Price: 25},
+			},
+			155,
+			false,
+		},
+		{
+			"19: bundle product not in checkout",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "C", Quantity: 2}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"A": 1, "B": 1, "C": 1}, "Price": 100}`)}},
+				"B": {Price: 35},
+				"C": {Price: 25},
+			},
+			150,
+			false,
+		},
+		{
+			"20: bundle not including product it is listed under",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"B": 1, "C": 1}, "Price": 100}`)}},
+			},
+			0,
+			true,
+		},
+		{
+			"21: any 3 from group, most expensive items used while offer is cheaper",
+			[]checkout.CheckoutLine{{Code: "X", Quantity: 2}, {Code: "Y", Quantity: 2}, {Code: "Z", Quantity: 2}},
+			map[string]checkout.Product{
+				"X": {Price: 30, Groups: []string{"drinks"}, Rules: []checkout.RuleSpec{rule("groupmultibuy", `{"Group": "drinks", "Quantity": 3, "Price": 100}`)}},
+				"Y": {Price: 40, Groups: []string{"drinks"}},
+				"Z": {Price: 25, Groups: []string{"drinks"}},
+			},
+			180,
+			false,
+		},
+		{
+			"22: cheapest of 3 from group free",
+			[]checkout.CheckoutLine{{Code: "X", Quantity: 2}, {Code: "Y", Quantity: 2}, {Code: "Z", Quantity: 2}},
+			map[string]checkout.Product{
+				"X": {Price: 30, Groups: []string{"drinks"}},
+				"Y": {Price: 40, Groups: []string{"drinks"}, Rules: []checkout.RuleSpec{rule("groupcheapestfree", `{"Group": "drinks", "Quantity": 3}`)}},
+				"Z": {Price: 25, Groups: []string{"drinks"}},
+			},
+			135,
+			false,
+		},
+		{
+			"23: group rule listed by every product in group applied once, products outside group ignored",
+			[]checkout.CheckoutLine{{Code: "X", Quantity: 3}, {Code: "Y", Quantity: 1}, {Code: "A", Quantity: 4}},
+			map[string]checkout.Product{
+				"A": {Price: 50},
+				"X": {Price: 30, Groups: []string{"drinks"}, Rules: []checkout.RuleSpec{rule("groupmultibuy", `{"Group": "drinks", "Quantity": 2, "Price": 50}`)}},
+				"Y": {Price: 40, Groups: []string{"drinks", "cold"}, Rules: []checkout.RuleSpec{rule("groupmultibuy", `{"Group": "drinks", "Quantity": 2, "Price": 50}`)}},
+			},
+			300,
+			false,
+		},
+		{
+			"24: group rule applied to items left over from product rules",
+			[]checkout.CheckoutLine{{Code: "X", Quantity: 3}, {Code: "Y", Quantity: 1}},
+			map[string]checkout.Product{
+				"X": {Price: 30, Groups: []string{"drinks"}, OfferQuantity: 2, OfferPrice: 50, Rules: []checkout.RuleSpec{rule("groupcheapestfree", `{"Group": "drinks", "Quantity": 2}`)}},
+				"Y": {Price: 40, Groups: []string{"drinks"}},
+			},
+			90,
+			false,
+		},
+		{
+			"25: cheapest from group free with large quantity",
+			[]checkout.CheckoutLine{{Code: "W", Quantity: 999999}, {Code: "X", Quantity: 2}},
+			map[string]checkout.Product{
+				"W": {Price: 10, Groups: []string{"snacks"}, Rules: []checkout.RuleSpec{rule("groupcheapestfree", `{"Group": "snacks", "Quantity": 3}`)}},
+				"X": {Price: 30, Groups: []string{"snacks"}},
+			},
+			6666720,
+			false,
+		},
+		{
+			"26: group rule with no group",
+			[]checkout.CheckoutLine{{Code: "X", Quantity: 3}},
+			map[string]checkout.Product{
+				"X": {Price: 30, Rules: []checkout.RuleSpec{rule("groupmultibuy", `{"Quantity": 2, "Price": 50}`)}},
+			},
+			0,
+			true,
+		},
+		{
+			"27: bundle not applied when it costs more than its items",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 2}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"A": 1, "B": 1}, "Price": 100}`)}},
+				"B": {Price: 35},
+			},
+			170,
+			false,
+		},
+		{
+			"28: bundle not applied when it costs the same as its items",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}, {Code: "B", Quantity: 1}},
+			map[string]checkout.Product{
+				"A": {Price: 50, Rules: []checkout.RuleSpec{rule("bundle", `{"Items": {"A": 1, "B": 1}, "Price": 85}`)}},
+				"B": {Price: 35},
+			},
+			85,
+			false,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.GetCheckoutPrice(testCase.checkoutLines, testCase.products)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			// compare result val to expected val
+			if result != testCase.expected {
+				t.Errorf("expected checkout price of: %v, got checkout price of: %v", testCase.expected, result)
+			}
+		})
+	}
+}
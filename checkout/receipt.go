@@ -0,0 +1,79 @@
+package checkout
+
+type (
+	// Receipt stores the itemized result of pricing a checkout.
+	//
+	// Lines holds a ReceiptLine for each checkout line, in the order they were given.
+	// Rejected holds the index of each checkout line rejected when pricing with the CollectErrors option, which have no ReceiptLine.
+	// Adjustments holds an Adjustment for each pricing rule applied to the checkout (e.g. one per bundle),
+	// with Subtotal being the gross price of the lines after these adjustments.
+	// BasketAdjustments holds an Adjustment for each basket rule applied to the checkout (e.g. spend 200, get 20 off).
+	// Taxes holds a TaxTotal for each tax class in the checkout, if a TaxTable was used to price it, with Tax being the total tax on the checkout.
+	// Total is the grand total of the checkout after basket adjustments and tax, tax only being added if prices are tax exclusive.
+	// Every amount on a receipt is in the currency of the basket.
+	//
+	// PriceCheckout returns a Receipt for a slice of CheckoutLine
+	Receipt struct {
+		Lines             []ReceiptLine
+		Rejected          []int
+		Adjustments       []Adjustment
+		Subtotal          Money
+		BasketAdjustments []Adjustment
+		Taxes             []TaxTotal
+		Tax               Money
+		Total             Money
+	}
+
+	// ReceiptLine stores the gross price of a checkout line, being its Quantity (or Measure, for products sold by weight or volume)
+	// at the UnitPrice of its product, before any pricing rules are applied.
+	ReceiptLine struct {
+		Code      string
+		Quantity  int
+		Measure   Measure
+		UnitPrice Money
+		Gross     Money
+	}
+)
+
+// Saving returns the saving made by an Adjustment, being its Amount negated (e.g. an Amount of -£0.20 is a saving of £0.20).
+func (a Adjustment) Saving() Money {
+	return a.Amount.Neg()
+}
+
+// Gross returns the gross price of the receipt lines, before any adjustments.
+//
+// An *OverflowError naming the product of the line is returned if the gross price is too large to be represented.
+func (r Receipt) Gross() (Money, error) {
+	var c checked
+	gross := Money{Currency: r.Total.Currency}
+	for _, line := range r.Lines {
+		if gross.Amount = c.add(gross.Amount, line.Gross.Amount); c.overflow {
+			return Money{}, &OverflowError{Line: -1, Code: line.Code, Amount: "gross"}
+		}
+	}
+	return gross, nil
+}
+
+// intTotal returns the Total of the receipt as an int, as returned by GetCheckoutPrice and ProcessCheckout.
+//
+// An *OverflowError is returned if the total cannot be represented as an int (e.g. on a 32-bit platform).
+func (r Receipt) intTotal() (int, error) {
+	if int64(int(r.Total.Amount)) != r.Total.Amount {
+		return 0, &OverflowError{Line: -1, Amount: "total"}
+	}
+	return int(r.Total.Amount), nil
+}
+
+// Savings returns the total saving made by the adjustments of pricing rules and basket rules.
+//
+// An *OverflowError is returned if the total saving is too large to be represented.
+func (r Receipt) Savings() (Money, error) {
+	var c checked
+	savings := Money{Currency: r.Total.Currency}
+	for _, adj := range append(append([]Adjustment{}, r.Adjustments...), r.BasketAdjustments...) {
+		if savings.Amount = c.sub(savings.Amount, adj.Amount.Amount); c.overflow {
+			return Money{}, &OverflowError{Line: -1, Amount: "savings"}
+		}
+	}
+	return savings, nil
+}
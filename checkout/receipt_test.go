@@ -0,0 +1,67 @@
+package checkout_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_Receipt tests the itemized Receipt returned by the ProcessCheckoutReceipt function,
+// and the Gross/ Savings methods of Receipt.
+func Test_Receipt(t *testing.T) {
+	receipt, err := checkout.ProcessCheckoutReceipt(
+		"../testdata/checkout_sets/1.json",
+		"../testdata/product_sets/1.json",
+		checkout.PricingOptions{BasketRules: []checkout.RuleSpec{rule("spendamountoff", `{"Threshold": 200, "Amount": 20}`)}},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got err: %s", err)
+	}
+
+	expected := checkout.Receipt{
+		Lines: []checkout.ReceiptLine{
+			{Code: "A", Quantity: 3, UnitPrice: gbp(50), Gross: gbp(150)},
+			{Code: "B", Quantity: 3, UnitPrice: gbp(35), Gross: gbp(105)},
+			{Code: "C", Quantity: 1, UnitPrice: gbp(25), Gross: gbp(25)},
+			{Code: "D", Quantity: 2, UnitPrice: gbp(12), Gross: gbp(24)},
+		},
+		Adjustments: []checkout.Adjustment{
+			{Rule: "multibuy", Description: "3 A for £1.40", Applications: 1, Claimed: map[string]int{"A": 3}, Amount: gbp(-10)},
+			{Rule: "multibuy", Description: "2 B for £0.60", Applications: 1, Claimed: map[string]int{"B": 2}, Amount: gbp(-10)},
+		},
+		Subtotal: gbp(284),
+		BasketAdjustments: []checkout.Adjustment{
+			{Rule: "spendamountoff", Description: "spend £2.00, get £0.20 off", Applications: 1, Claimed: map[string]int{"A": 3, "B": 3, "C": 1, "D": 2}, Amount: gbp(-20)},
+		},
+		Tax:   gbp(0),
+		Total: gbp(264),
+	}
+
+	if !reflect.DeepEqual(receipt, expected) {
+		t.Errorf("expected receipt:\n%+v\ngot receipt:\n%+v", expected, receipt)
+	}
+	if gross, err := receipt.Gross(); gross != gbp(304) || err != nil {
+		t.Errorf("expected gross of: £3.04, got gross of: %v, err: %v", gross, err)
+	}
+	if savings, err := receipt.Savings(); savings != gbp(40) || err != nil {
+		t.Errorf("expected savings of: £0.40, got savings of: %v, err: %v", savings, err)
+	}
+
+	// check gross and savings too large to be represented return an *OverflowError
+	receipt = checkout.Receipt{
+		Lines:             []checkout.ReceiptLine{{Code: "A", Gross: gbp(math.MaxInt64)}, {Code: "B", Gross: gbp(1)}},
+		Adjustments:       []checkout.Adjustment{{Amount: gbp(math.MinInt64)}},
+		BasketAdjustments: []checkout.Adjustment{{Amount: gbp(-1)}},
+		Total:             gbp(0),
+	}
+	var overflowErr *checkout.OverflowError
+	if _, err := receipt.Gross(); !errors.As(err, &overflowErr) || err.Error() != `product "B": gross overflows` {
+		t.Errorf("expected gross overflow of product B, got err: %v", err)
+	}
+	if _, err := receipt.Savings(); !errors.As(err, &overflowErr) || err.Error() != "savings overflows" {
+		t.Errorf("expected savings overflow, got err: %v", err)
+	}
+}
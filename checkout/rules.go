@@ -0,0 +1,216 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// NoLimit can be passed to PricingRule.Apply to apply a rule as many times as the basket allows.
+const NoLimit = -1
+
+type (
+	// PricingRule is implemented by each type of promotion which can be applied to a checkout.
+	//
+	// Apply evaluates the rule against the quantities in the Basket which have not yet been claimed by another rule,
+	// claims the quantities it uses, and returns an Adjustment describing the change made to the checkout total.
+	// The rule is applied at most limit times, or as many times as the basket allows if limit is NoLimit.
+	// If the rule cannot be applied, the returned Adjustment has 0 Applications.
+	PricingRule interface {
+		Apply(basket *Basket, limit int) (Adjustment, error)
+	}
+
+	// Adjustment describes a change made to the checkout total by a PricingRule.
+	//
+	// Rule is the type name of the rule (e.g. "multibuy"), with Description being a readable summary of the rule (e.g. "3 A for £1.40").
+	// Claimed holds the quantity of each product code used by the rule.
+	// Amount is the change to the checkout total in the currency of the basket, a negative Amount being a saving.
+	Adjustment struct {
+		Rule         string
+		Description  string
+		Applications int
+		Claimed      map[string]int
+		Amount       Money
+	}
+
+	// RuleSpec references a PricingRule by its type name, and is found in the Rules of a Product.
+	//
+	// Params holds the JSON parameters of the rule, which are decoded by the RuleFactory registered for Type
+	// (e.g. {"Type": "multibuy", "Params": {"Quantity": 3, "Price": 140}}).
+	RuleSpec struct {
+		Type   string
+		Params json.RawMessage
+	}
+
+	// basketLevelRule is implemented by pricing rules which are evaluated across the whole basket rather than the product they are listed under,
+	// key identifies the rule so it is only applied once when listed by several products.
+	basketLevelRule interface {
+		PricingRule
+		key() string
+	}
+
+	// RuleFactory builds a PricingRule for the product with the given code from the JSON parameters of a RuleSpec.
+	RuleFactory func(code string, params json.RawMessage) (PricingRule, error)
+)
+
+var (
+	// ruleFactoriesMu guards ruleFactories
+	ruleFactoriesMu sync.RWMutex
+
+	// ruleFactories maps rule type names to the RuleFactory used to build them
+	ruleFactories = map[string]RuleFactory{
+		"multibuy":   newMultiBuyRule,
+		"percentoff": newPercentOffRule,
+		"amountoff":  newAmountOffRule,
+		"buyxgety":   newBuyXGetYRule,
+		"bundle":     newBundleRule,
+
+		"groupmultibuy":     newGroupMultiBuyRule,
+		"groupcheapestfree": newGroupCheapestFreeRule,
+	}
+)
+
+// RegisterRule makes a type of PricingRule available to be referenced by name from the Rules of a Product.
+//
+// An error is returned if ruleType is empty, factory is nil, or a rule is already registered with the same name.
+func RegisterRule(ruleType string, factory RuleFactory) error {
+	if ruleType == "" {
+		return errors.New("rule type cannot be empty")
+	}
+	if factory == nil {
+		return errors.New("rule factory cannot be nil")
+	}
+
+	ruleFactoriesMu.Lock()
+	defer ruleFactoriesMu.Unlock()
+
+	if _, ok := ruleFactories[ruleType]; ok {
+		return fmt.Errorf("rule type %q is already registered", ruleType)
+	}
+	ruleFactories[ruleType] = factory
+
+	return nil
+}
+
+// NewPricingRule builds the PricingRule referenced by a RuleSpec, for the product with the given code.
+//
+// An error is returned if no rule is registered with the type name of the RuleSpec, or if its parameters are invalid.
+func NewPricingRule(code string, spec RuleSpec) (PricingRule, error) {
+
+	rule, err := buildPricingRule(code, spec)
+	if err != nil {
+		return nil, fmt.Errorf("product %q: %w", code, err)
+	}
+
+	return rule, nil
+}
+
+// buildPricingRule builds the PricingRule referenced by a RuleSpec, returning errors without the product code.
+func buildPricingRule(code string, spec RuleSpec) (PricingRule, error) {
+
+	ruleFactoriesMu.RLock()
+	factory, ok := ruleFactories[spec.Type]
+	ruleFactoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown pricing rule type %q", spec.Type)
+	}
+
+	rule, err := factory(code, spec.Params)
+	if err != nil {
+		return nil, fmt.Errorf("%s rule: %w", spec.Type, err)
+	}
+
+	return rule, nil
+}
+
+// PricingRules returns the pricing rules of a Product, for the given product code.
+//
+// If Tiers are set, a tiers rule is returned first, if OfferQuantity is set, a multibuy rule is returned next,
+// followed by a rule for each RuleSpec in Rules.
+//
+// An error is returned if the offer quantity is negative, the tiers are invalid, the unit of measure is invalid,
+// or a rule cannot be built from its RuleSpec.
+// Errors are prefixed with the product code.
+func (p Product) PricingRules(code string) ([]PricingRule, error) {
+
+	rules, err := p.pricingRules(code)
+	if err != nil {
+		return nil, fmt.Errorf("product %q: %w", code, err)
+	}
+
+	return rules, nil
+}
+
+// pricingRules returns the pricing rules of a Product as PricingRules does, returning errors without the product code.
+func (p Product) pricingRules(code string) ([]PricingRule, error) {
+
+	if err := p.validateUnit(); err != nil {
+		return nil, err
+	}
+
+	rules := []PricingRule{}
+
+	if len(p.Tiers) > 0 {
+		if err := p.validateTiers(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, tiersRule{Code: code, Mode: p.TierMode, Tiers: p.Tiers})
+	}
+
+	// check for invalid offer quantity
+	if p.OfferQuantity < 0 {
+		return nil, errors.New("offer quantity cannot be negative")
+	}
+	// check if there is an offer to be used
+	if p.OfferQuantity > 0 {
+		rules = append(rules, multiBuyRule{Code: code, Quantity: p.OfferQuantity, Price: p.OfferPrice, legacy: true})
+	}
+
+	for _, spec := range p.Rules {
+		rule, err := buildPricingRule(code, spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// decodeRuleParams unmarshals the JSON parameters of a RuleSpec into v, a pointer to a struct, treating missing parameters as an empty object.
+//
+// A parameter which is not a field of the struct (e.g. a misspelt "GetCod") is returned as a *fieldError at its path (e.g. "Params.GetCod"),
+// rather than being ignored.
+func decodeRuleParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if json.Unmarshal(params, &fields) == nil {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !knownField(reflect.TypeOf(v).Elem(), name) {
+				return &fieldError{Path: joinPath("Params", name), Err: errors.New("unknown field")}
+			}
+		}
+	}
+
+	return json.Unmarshal(params, v)
+}
+
+// applications returns how many times a rule can be applied given the number of times the basket allows (available) and a limit.
+func applications(available int, limit int) int {
+	if limit != NoLimit && available > limit {
+		return limit
+	}
+	return available
+}
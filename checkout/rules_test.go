@@ -0,0 +1,222 @@
+package checkout_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// halfPriceRule is a PricingRule registered by Test_RegisterRule, pricing every unit of its product at half price.
+type halfPriceRule struct {
+	code string
+}
+
+func (r halfPriceRule) Apply(basket *checkout.Basket, limit int) (checkout.Adjustment, error) {
+	prod, _ := basket.Product(r.code)
+	n := basket.Remaining(r.code)
+	if limit != checkout.NoLimit && n > limit {
+		n = limit
+	}
+	if n == 0 {
+		return checkout.Adjustment{}, nil
+	}
+	if err := basket.Claim(r.code, n); err != nil {
+		return checkout.Adjustment{}, err
+	}
+	return checkout.Adjustment{
+		Rule:         "halfprice",
+		Applications: n,
+		Claimed:      map[string]int{r.code: n},
+		Amount:       checkout.Money{Amount: int64(-n * prod.Price / 2), Currency: basket.Currency()},
+	}, nil
+}
+
+// Test_RegisterRule tests the RegisterRule function, registering a new rule type and using it to price a checkout,
+// and checking registering an invalid/ duplicate rule type returns an error.
+func Test_RegisterRule(t *testing.T) {
+	factory := func(code string, params json.RawMessage) (checkout.PricingRule, error) {
+		return halfPriceRule{code}, nil
+	}
+
+	if err := checkout.RegisterRule("halfprice", factory); err != nil {
+		t.Fatalf("expected no error registering rule, got err: %s", err)
+	}
+
+	// registered rule can be referenced from products
+	result, err := checkout.GetCheckoutPrice(
+		[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 2}},
+		map[string]checkout.Product{
+			"A": {Price: 50, Rules: []checkout.RuleSpec{{Type: "halfprice"}}},
+			"B": {Price: 35},
+		},
+	)
+	if err != nil {
+		t.Errorf("expected no error, got err: %s", err)
+	}
+	if result != 145 {
+		t.Errorf("expected checkout price of: 145, got checkout price of: %v", result)
+	}
+
+	testCases := []struct {
+		name     string
+		ruleType string
+		factory  checkout.RuleFactory
+	}{
+		{"1: duplicate rule type", "halfprice", factory},
+		{"2: existing built in rule type", "multibuy", factory},
+		{"3: empty rule type", "", factory},
+		{"4: nil factory", "other", nil},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			if err := checkout.RegisterRule(testCase.ruleType, testCase.factory); err == nil {
+				t.Errorf("expected error registering rule type %q, got nil", testCase.ruleType)
+			}
+		})
+	}
+}
+
+// Test_NewPricingRule tests the NewPricingRule function.
+//
+// It passes a RuleSpec to the function, and applies the returned rule to a basket of 7 "A",
+// checking the adjustment made, and whether or not an error is expected.
+func Test_NewPricingRule(t *testing.T) {
+	testCases := []struct {
+		name           string
+		spec           checkout.RuleSpec
+		limit          int
+		expApplication int
+		expAmount      int
+		expErr         bool
+	}{
+		{
+			"1: multibuy rule",
+			checkout.RuleSpec{Type: "multibuy", Params: json.RawMessage(`{"Quantity": 3, "Price": 140}`)},
+			checkout.NoLimit,
+			2,
+			-20,
+			false,
+		},
+		{
+			"2: multibuy rule limited to 1 application",
+			checkout.RuleSpec{Type: "multibuy", Params: json.RawMessage(`{"Quantity": 3, "Price": 140}`)},
+			1,
+			1,
+			-10,
+			false,
+		},
+		{
+			"3: multibuy rule quantity more than basket quantity",
+			checkout.RuleSpec{Type: "multibuy", Params: json.RawMessage(`{"Quantity": 8, "Price": 140}`)},
+			checkout.NoLimit,
+			0,
+			0,
+			false,
+		},
+		{
+			"4: multibuy rule with no quantity",
+			checkout.RuleSpec{Type: "multibuy", Params: json.RawMessage(`{"Price": 140}`)},
+			checkout.NoLimit,
+			0,
+			0,
+			true,
+		},
+		{
+			"5: multibuy rule with invalid params",
+			checkout.RuleSpec{Type: "multibuy", Params: json.RawMessage(`{"Quantity": "three"}`)},
+			checkout.NoLimit,
+			0,
+			0,
+			true,
+		},
+		{
+			"6: unknown rule type",
+			checkout.RuleSpec{Type: "fake"},
+			checkout.NoLimit,
+			0,
+			0,
+			true,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			rule, err := checkout.NewPricingRule("A", testCase.spec)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Fatalf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			basket, err := checkout.NewBasket(
+				[]checkout.CheckoutLine{{Code: "A", Quantity: 7}},
+				map[string]checkout.Product{"A": {Price: 50}},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			adj, err := rule.Apply(basket, testCase.limit)
+			if err != nil {
+				t.Fatalf("expected no error applying rule, got err: %s", err)
+			}
+			// compare adjustment to expected vals
+			if adj.Applications != testCase.expApplication {
+				t.Errorf("expected applications: %v, got applications: %v", testCase.expApplication, adj.Applications)
+			}
+			if adj.Amount.Amount != int64(testCase.expAmount) {
+				t.Errorf("expected adjustment amount: %v, got amount: %v", testCase.expAmount, adj.Amount)
+			}
+			if claimed := 7 - basket.Remaining("A"); claimed != adj.Claimed["A"] {
+				t.Errorf("expected claimed quantity: %v, got claimed quantity: %v", claimed, adj.Claimed["A"])
+			}
+		})
+	}
+}
+
+// Test_PricingRules tests the PricingRules method of Product, checking the number of rules returned,
+// and whether or not an error is expected.
+func Test_PricingRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		product  checkout.Product
+		expRules int
+		expErr   bool
+	}{
+		{"1: no offer or rules", checkout.Product{Price: 5}, 0, false},
+		{"2: offer", checkout.Product{Price: 5, OfferQuantity: 2, OfferPrice: 8}, 1, false},
+		{
+			"3: offer and rules",
+			checkout.Product{Price: 5, OfferQuantity: 2, OfferPrice: 8, Rules: []checkout.RuleSpec{
+				{Type: "multibuy", Params: json.RawMessage(`{"Quantity": 5, "Price": 18}`)},
+			}},
+			2,
+			false,
+		},
+		{"4: negative offer quantity", checkout.Product{Price: 5, OfferQuantity: -2, OfferPrice: 8}, 0, true},
+		{"5: unknown rule type", checkout.Product{Price: 5, Rules: []checkout.RuleSpec{{Type: "fake"}}}, 0, true},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			rules, err := testCase.product.PricingRules("A")
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			if len(rules) != testCase.expRules {
+				t.Errorf("expected %v rules, got %v rules", testCase.expRules, len(rules))
+			}
+		})
+	}
+}
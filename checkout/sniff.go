@@ -0,0 +1,100 @@
+package checkout
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// gzipMagic and zstdMagic are the bytes gzip and zstd compressed data start with.
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompress returns a reader of the data read from r, decompressing it if it is gzip or zstd compressed,
+// along with the name of the data with any .gz or .zst extension removed, so the Format of the data can be found from its name.
+//
+// The returned close function releases the decompressor, and must be called once the data has been read.
+func decompress(name string, r io.Reader) (io.Reader, string, func(), error) {
+
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("reading gzip data: %w", err)
+		}
+		return gr, trimExt(name, ".gz", ".gzip"), func() { gr.Close() }, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("reading zstd data: %w", err)
+		}
+		return zr, trimExt(name, ".zst", ".zstd"), zr.Close, nil
+	}
+
+	return br, name, func() {}, nil
+}
+
+// trimExt removes any of exts from the end of name, matched case insensitively.
+func trimExt(name string, exts ...string) string {
+	for _, ext := range exts {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// sniffCheckout decodes checkout data in data, read from file, finding its format from its content:
+// data starting with "[" is a JSON array of checkout lines, data whose first line is a JSON object is NDJSON,
+// and data with a CSV header row naming checkout line fields is CSV. Empty data is decoded as JSON.
+//
+// If the data matches none of these a *SyntaxError is returned, listing the formats tried and why each did not match.
+func sniffCheckout(file string, data []byte) ([]CheckoutLine, []Position, error) {
+
+	start := bytes.IndexFunc(data, func(r rune) bool {
+		return !strings.ContainsRune(" \t\r\n", r)
+	})
+
+	if start < 0 || data[start] == '[' {
+		return decodeCheckout(file, data)
+	}
+	if data[start] == '{' {
+		firstLine := data[start:]
+		if end := bytes.IndexByte(firstLine, '\n'); end >= 0 {
+			firstLine = firstLine[:end]
+		}
+		if json.Valid(firstLine) {
+			return readCheckoutNDJSON(file, bytes.NewReader(data))
+		}
+		// a JSON object spread over several lines (which is not an array of checkout lines)
+		return decodeCheckout(file, data)
+	}
+
+	// data with a checkout CSV header is CSV, even if a later row is invalid
+	cLSlice, positions, csvErr := readCheckoutCSV(file, bytes.NewReader(data), CSVOptions{})
+	var rowErr *CSVError
+	if csvErr == nil || (errors.As(csvErr, &rowErr) && rowErr.Row > 1) {
+		return cLSlice, positions, csvErr
+	}
+
+	_, _, jsonErr := decodeCheckout(file, data)
+	_, _, ndjsonErr := readCheckoutNDJSON(file, bytes.NewReader(data))
+
+	return nil, nil, &SyntaxError{
+		Pos: position(file, data, int64(start)),
+		Err: fmt.Errorf("unrecognised checkout data format, tried JSON array (%v), NDJSON (%v) and CSV (%v)", jsonErr, ndjsonErr, csvErr),
+	}
+}
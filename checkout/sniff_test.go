@@ -0,0 +1,82 @@
+package checkout_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_DecodeCheckoutDataSniff tests the format of checkout files is found from their content,
+// and that compressed files are decompressed.
+func Test_DecodeCheckoutDataSniff(t *testing.T) {
+	example := []checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 3}, {Code: "C", Quantity: 1}, {Code: "D", Quantity: 2}}
+
+	testCases := []struct {
+		name     string
+		filePath string
+		expected []checkout.CheckoutLine
+	}{
+		{"1: gzip compressed JSON", "../testdata/checkout_sets/20.json.gz", example},
+		{"2: zstd compressed JSON", "../testdata/checkout_sets/21.json.zst", example},
+		{"3: CSV without a .csv extension", "../testdata/checkout_sets/22.txt", example},
+		{"4: NDJSON without a .ndjson extension", "../testdata/checkout_sets/23.txt", []checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 1}, {Code: "C", Quantity: 1}}},
+		{"5: gzip compressed CSV", "../testdata/checkout_sets/25.csv.gz", example},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.DecodeCheckoutData(testCase.filePath)
+			if err != nil {
+				t.Fatalf("expected no error, got err: %v", err)
+			}
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected: %v, got %v", testCase.expected, result)
+			}
+		})
+	}
+
+	// check data in no known format lists the formats tried
+	_, err := checkout.DecodeCheckoutData("../testdata/checkout_sets/24.txt")
+	var syntaxErr *checkout.SyntaxError
+	expErr := `../testdata/checkout_sets/24.txt:1:1: unrecognised checkout data format, tried JSON array (invalid character 'o' in literal true (expecting 'r')), ` +
+		`NDJSON (each line of NDJSON checkout data must be a JSON object) and CSV (row 1, column "total: 4 items": unknown column)`
+	if !errors.As(err, &syntaxErr) || checkout.FormatError(err) != expErr {
+		t.Errorf("expected err: %s, got err: %s", expErr, checkout.FormatError(err))
+	}
+
+	// check a CSV file with an invalid row reports the row, rather than the formats tried
+	_, err = checkout.DecodeCheckoutReader(bytes.NewReader([]byte("code,quantity\nA,two\n")))
+	var csvErr *checkout.CSVError
+	if !errors.As(err, &csvErr) || csvErr.Row != 2 {
+		t.Errorf("expected *CSVError for row 2, got err: %v", err)
+	}
+
+	// check compressed products files and readers are decompressed
+	products, err := checkout.DecodeProductData("../testdata/product_sets/26.json.gz")
+	if err != nil || len(products) != 4 {
+		t.Errorf("expected 4 products, got %d, err: %v", len(products), err)
+	}
+	data, err := os.ReadFile("../testdata/checkout_sets/1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+	if receipt, err := checkout.StreamCheckout(bytes.NewReader(compressed.Bytes()), products, checkout.PricingOptions{}); err != nil || receipt.Total != gbp(284) {
+		t.Errorf("expected checkout price of: 284, got checkout price of: %v, err: %v", receipt.Total, err)
+	}
+
+	// check corrupt compressed data returns an error
+	if _, err := checkout.DecodeCheckoutReader(bytes.NewReader(compressed.Bytes()[:20])); err == nil {
+		t.Errorf("expected error for truncated gzip data, got no err")
+	}
+}
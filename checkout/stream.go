@@ -0,0 +1,312 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+type (
+	// CheckoutDecoder reads checkout lines one at a time from a JSON array of checkout lines,
+	// so a checkout too large to hold in memory can be priced with a Pricer (see StreamCheckout).
+	//
+	// Only the line being decoded is held in memory, with errors being located as by DecodeCheckoutData.
+	CheckoutDecoder struct {
+		file    string
+		counter *lineCounter
+		dec     *json.Decoder
+		started bool
+		pos     Position
+		err     error
+	}
+
+	// Pricer prices a checkout incrementally, with each checkout line being merged into its Basket as it is added,
+	// so the memory used depends on the number of products in the checkout rather than the number of checkout lines.
+	//
+	// Pricing rules, basket rules and tax are applied by Receipt, which can be called at any point to price the lines added so far.
+	Pricer struct {
+		basket   *Basket
+		opts     PricingOptions
+		next     int
+		lineErrs LineErrors
+		err      error
+	}
+
+	// lineCounter wraps a reader, recording the offset of each newline read so the Position of an offset can be found
+	// without keeping what has been read. Only the newlines after the last offset located are kept,
+	// so offsets must be located in order.
+	lineCounter struct {
+		r           io.Reader
+		read        int64   // number of bytes read
+		first       int64   // offset of the first byte read which is not JSON whitespace, or -1
+		newlines    []int64 // offsets of the newlines read after the last offset located
+		line        int     // number of newlines before the last offset located
+		lastNewline int64   // offset of the last newline before the last offset located, or -1
+	}
+)
+
+// NewCheckoutDecoder returns a CheckoutDecoder reading checkout JSON from r. The Position of errors has no File.
+func NewCheckoutDecoder(r io.Reader) *CheckoutDecoder {
+	return newCheckoutDecoder("", r)
+}
+
+// newCheckoutDecoder returns a CheckoutDecoder reading checkout JSON from r, named file.
+func newCheckoutDecoder(file string, r io.Reader) *CheckoutDecoder {
+	counter := &lineCounter{r: r, first: -1, lastNewline: -1}
+	return &CheckoutDecoder{file: file, counter: counter, dec: json.NewDecoder(counter)}
+}
+
+// Next returns the next checkout line, or io.EOF once every line has been read and the closing bracket of the array found.
+//
+// An error decoding the data is returned as a *SyntaxError, as by DecodeCheckoutData, after which Next returns the same error.
+func (d *CheckoutDecoder) Next() (CheckoutLine, error) {
+	if d.err != nil {
+		return CheckoutLine{}, d.err
+	}
+
+	cL, err := d.next()
+	if err != nil {
+		d.err = err
+		return CheckoutLine{}, err
+	}
+
+	return cL, nil
+}
+
+// Position returns the Position of the checkout line last returned by Next.
+func (d *CheckoutDecoder) Position() Position {
+	return d.pos
+}
+
+// next reads the next checkout line, reading the opening bracket of the array first.
+func (d *CheckoutDecoder) next() (CheckoutLine, error) {
+
+	if !d.started {
+		d.started = true
+		tok, err := d.dec.Token()
+		if err != nil {
+			return CheckoutLine{}, d.syntaxError(0, 0, err)
+		}
+		if tok != json.Delim('[') {
+			return CheckoutLine{}, &SyntaxError{Pos: d.at(d.counter.first), Err: errors.New("checkout data must be a JSON array of checkout lines")}
+		}
+	}
+
+	if !d.dec.More() {
+		return CheckoutLine{}, d.end()
+	}
+
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return CheckoutLine{}, d.syntaxError(0, d.dec.InputOffset(), err)
+	}
+	start := d.dec.InputOffset() - int64(len(raw))
+
+	var cL CheckoutLine
+	if err := json.Unmarshal(raw, &cL); err != nil {
+		return CheckoutLine{}, d.syntaxError(start, start, err)
+	}
+
+	d.pos = d.at(start)
+	return cL, nil
+}
+
+// end reads the closing bracket of the array, and checks nothing follows it, returning io.EOF if not.
+func (d *CheckoutDecoder) end() error {
+
+	if _, err := d.dec.Token(); err != nil {
+		return d.syntaxError(0, d.dec.InputOffset(), err)
+	}
+
+	// fill the buffer of the decoder up to the next value, to find where any data after the array starts
+	end := d.dec.InputOffset()
+	d.dec.More()
+	buffered, _ := io.ReadAll(d.dec.Buffered())
+	end += skipSeparators(buffered, 0)
+
+	if _, err := d.dec.Token(); err != io.EOF {
+		return &SyntaxError{Pos: d.at(end), Err: errors.New("invalid data after checkout lines")}
+	}
+
+	return io.EOF
+}
+
+// at returns the Position of an offset in the checkout data.
+func (d *CheckoutDecoder) at(offset int64) Position {
+	return d.counter.position(d.file, offset)
+}
+
+// syntaxError returns an error decoding the checkout data as a *SyntaxError, as syntaxError does for data in memory.
+func (d *CheckoutDecoder) syntaxError(base int64, start int64, err error) *SyntaxError {
+	return jsonSyntaxError(d.at, d.counter.read, base, start, err)
+}
+
+// Read reads from the wrapped reader, recording the offset of each newline.
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for i, b := range p[:n] {
+		switch b {
+		case '\n':
+			c.newlines = append(c.newlines, c.read+int64(i))
+		case ' ', '\t', '\r':
+		default:
+			if c.first < 0 {
+				c.first = c.read + int64(i)
+			}
+		}
+	}
+	c.read += int64(n)
+	return n, err
+}
+
+// position returns the Position of an offset in the data read, which must not be before the last offset located.
+func (c *lineCounter) position(file string, offset int64) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > c.read {
+		offset = c.read
+	}
+	for len(c.newlines) > 0 && c.newlines[0] < offset {
+		c.lastNewline = c.newlines[0]
+		c.line++
+		c.newlines = c.newlines[1:]
+	}
+	return Position{
+		File:   file,
+		Offset: offset,
+		Line:   c.line + 1,
+		Column: int(offset - c.lastNewline),
+	}
+}
+
+// NewPricer returns a Pricer for a checkout of products, priced using opts.
+func NewPricer(products map[string]Product, opts PricingOptions) *Pricer {
+	return &Pricer{basket: newBasket(products), opts: opts}
+}
+
+// Add merges the next checkout line into the basket, returning the *LineError or *OverflowError of NewBasket
+// naming the line by its index in the lines added.
+//
+// If opts.CollectErrors is true, a line which cannot be added is rejected, and lines can still be added after it.
+// Otherwise the Pricer fails, with Add and Receipt returning the error of the line from then on.
+func (p *Pricer) Add(cL CheckoutLine) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	i := p.next
+	p.next++
+
+	if p.opts.CollectErrors {
+		if err := p.basket.addCollected(i, cL); err != nil {
+			p.lineErrs.Errors = append(p.lineErrs.Errors, err)
+			return err
+		}
+		return nil
+	}
+
+	if err := p.basket.add(i, cL); err != nil {
+		p.err = err
+		return err
+	}
+
+	return nil
+}
+
+// Receipt prices the checkout lines added so far, as PriceCheckout does, except the Receipt has one ReceiptLine for each product code,
+// holding the total quantity or measure of its lines, in the order the product codes were first added.
+//
+// If opts.CollectErrors is true, the Receipt marks the Rejected lines, and is returned along with a *LineErrors holding the error of each.
+func (p *Pricer) Receipt() (Receipt, error) {
+	if p.err != nil {
+		return Receipt{}, p.err
+	}
+
+	// price a copy of the basket, so rules can claim its quantities without changing the lines added
+	basket := p.basket.clone()
+	receipt := Receipt{
+		Lines:    make([]ReceiptLine, 0, len(basket.codes)),
+		Subtotal: basket.money(0),
+		Tax:      basket.money(0),
+	}
+
+	var err error
+	for _, code := range basket.codes {
+		prod, _ := basket.Product(code)
+		gross := basket.money(basket.gross[code])
+		receipt.Lines = append(receipt.Lines, ReceiptLine{Code: code, Quantity: basket.quantity[code], Measure: basket.measure[code], UnitPrice: prod.UnitPrice(), Gross: gross})
+		if receipt.Subtotal, err = receipt.Subtotal.Add(gross); err != nil {
+			return Receipt{}, basket.overflowError(code, "subtotal")
+		}
+	}
+
+	if receipt, err = priceBasket(basket, receipt, p.opts); err != nil {
+		return Receipt{}, err
+	}
+
+	if len(p.lineErrs.Errors) > 0 {
+		lineErrs := &LineErrors{Errors: append([]error{}, p.lineErrs.Errors...)}
+		receipt.Rejected = lineErrs.Lines()
+		return receipt, lineErrs
+	}
+
+	return receipt, nil
+}
+
+// StreamCheckout prices a checkout read from a JSON array of checkout lines in r, decoding and pricing one line at a time
+// with a CheckoutDecoder and a Pricer, so memory stays flat however many lines the checkout has.
+//
+// Returned is the Receipt from Pricer.Receipt, and any error decoding or pricing the checkout.
+// The *LineError or *OverflowError of a line which cannot be added has the Position of the line set, the Position has no File.
+// If opts.CollectErrors is true, the partial Receipt is returned along with a *LineErrors, as by PriceCheckout.
+// gzip and zstd compressed data is decompressed as it is read.
+func StreamCheckout(r io.Reader, products map[string]Product, opts PricingOptions) (Receipt, error) {
+	return streamCheckout("", r, products, opts)
+}
+
+// StreamCheckoutFile is StreamCheckout reading the checkout JSON from a file, with the Position of errors naming the file.
+func StreamCheckoutFile(filePath string, products map[string]Product, opts PricingOptions) (Receipt, error) {
+
+	// open file to read from
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer file.Close()
+
+	return streamCheckout(filePath, file, products, opts)
+}
+
+// streamCheckout prices a checkout read from r, named file.
+func streamCheckout(file string, r io.Reader, products map[string]Product, opts PricingOptions) (Receipt, error) {
+
+	r, _, done, err := decompress(file, r)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer done()
+
+	dec := newCheckoutDecoder(file, r)
+	pricer := NewPricer(products, opts)
+
+	for {
+		cL, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Receipt{}, err
+		}
+
+		if err := pricer.Add(cL); err != nil {
+			locateAt(err, dec.Position())
+			if !opts.CollectErrors {
+				return Receipt{}, err
+			}
+		}
+	}
+
+	return pricer.Receipt()
+}
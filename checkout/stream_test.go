@@ -0,0 +1,275 @@
+package checkout_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_CheckoutDecoder tests checkout lines decoded one at a time match those decoded by DecodeCheckoutData,
+// and decoding errors are located as they are by DecodeCheckoutData.
+func Test_CheckoutDecoder(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filePath string
+	}{
+		{"1: example data", "../testdata/checkout_sets/1.json"},
+		{"2: other checkout data", "../testdata/checkout_sets/2.json"},
+		{"3: measured lines", "../testdata/checkout_sets/9.json"},
+		{"4: object rather than array", "../testdata/checkout_sets/8.json"},
+		{"5: blank file", "../testdata/checkout_sets/0.txt"},
+		{"6: missing comma between lines", "../testdata/checkout_sets/12.json"},
+		{"7: quantity of the wrong type", "../testdata/checkout_sets/13.json"},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			expected, expErr := checkout.DecodeCheckoutData(testCase.filePath)
+
+			data, err := os.ReadFile(testCase.filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := checkout.NewCheckoutDecoder(bytes.NewReader(data))
+			cLSlice := []checkout.CheckoutLine{}
+			for {
+				cL, err := dec.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					// compare the error, and its position, to the error decoding the file
+					var syntaxErr, expSyntaxErr *checkout.SyntaxError
+					if !errors.As(err, &syntaxErr) || !errors.As(expErr, &expSyntaxErr) {
+						t.Fatalf("expected err: %v, got err: %v", expErr, err)
+					}
+					expPos := expSyntaxErr.Pos
+					expPos.File = ""
+					if syntaxErr.Pos != expPos || syntaxErr.Error() != expSyntaxErr.Error() {
+						t.Errorf("expected err: %v at %+v, got err: %v at %+v", expSyntaxErr, expPos, syntaxErr, syntaxErr.Pos)
+					}
+					// check the decoder keeps returning the error
+					if _, again := dec.Next(); again != err {
+						t.Errorf("expected the same error from Next, got err: %v", again)
+					}
+					return
+				}
+				cLSlice = append(cLSlice, cL)
+			}
+
+			if expErr != nil {
+				t.Fatalf("expected err: %v, got no err", expErr)
+			}
+			if !reflect.DeepEqual(cLSlice, expected) {
+				t.Errorf("expected: %v, got %v", expected, cLSlice)
+			}
+		})
+	}
+
+	// check data after the closing bracket of the array is located
+	dec := checkout.NewCheckoutDecoder(strings.NewReader("[{\"code\": \"A\", \"quantity\": 1}]\n\n  x"))
+	if _, err := dec.Next(); err != nil {
+		t.Fatalf("expected no error, got err: %v", err)
+	}
+	_, err := dec.Next()
+	var syntaxErr *checkout.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Pos.Line != 3 || syntaxErr.Pos.Column != 3 {
+		t.Errorf("expected *SyntaxError at 3:3, got err: %v", err)
+	}
+}
+
+// Test_StreamCheckout tests streamed checkouts are priced as by ProcessCheckoutReceipt, with their receipt lines merged by product code,
+// and that errors pricing a line are located in the checkout file.
+func Test_StreamCheckout(t *testing.T) {
+	testCases := []struct {
+		name         string
+		checkoutPath string
+		productsPath string
+		opts         checkout.PricingOptions
+	}{
+		{"1: example data", "../testdata/checkout_sets/1.json", "../testdata/product_sets/1.json", checkout.PricingOptions{}},
+		{"2: optimal allocation", "../testdata/checkout_sets/1.json", "../testdata/product_sets/1.json", checkout.PricingOptions{Allocation: checkout.AllocationOptimal}},
+		{
+			"3: basket rules and tax",
+			"../testdata/checkout_sets/1.json",
+			"../testdata/product_sets/13.json",
+			checkout.PricingOptions{
+				BasketRules: []checkout.RuleSpec{rule("spendpercentoff", `{"Threshold": 100, "Percent": 10}`)},
+				Tax:         &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: 2000, checkout.TaxClassReduced: 500}},
+			},
+		},
+		{"4: measured lines", "../testdata/checkout_sets/9.json", "../testdata/product_sets/15.json", checkout.PricingOptions{}},
+		{"5: collect errors", "../testdata/checkout_sets/14.json", "../testdata/product_sets/1.json", checkout.PricingOptions{CollectErrors: true}},
+		{"6: unknown product", "../testdata/checkout_sets/10.json", "../testdata/product_sets/1.json", checkout.PricingOptions{}},
+		{"7: negative quantity", "../testdata/checkout_sets/11.json", "../testdata/product_sets/1.json", checkout.PricingOptions{}},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			expected, expErr := checkout.ProcessCheckoutReceipt(testCase.checkoutPath, testCase.productsPath, testCase.opts)
+
+			products, err := checkout.DecodeProductData(testCase.productsPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			receipt, err := checkout.StreamCheckoutFile(testCase.checkoutPath, products, testCase.opts)
+
+			// check errors match, and are formatted with the same positions
+			if (err != nil) != (expErr != nil) {
+				t.Fatalf("expected err: %v, got err: %v", expErr, err)
+			}
+			if err != nil && checkout.FormatError(err) != checkout.FormatError(expErr) {
+				t.Errorf("expected err:\n%s\ngot err:\n%s", checkout.FormatError(expErr), checkout.FormatError(err))
+			}
+
+			// check totals match, with the lines of each product code merged
+			if receipt.Total != expected.Total || receipt.Subtotal != expected.Subtotal || receipt.Tax != expected.Tax {
+				t.Errorf("expected totals: %v %v %v, got totals: %v %v %v", expected.Subtotal, expected.Tax, expected.Total, receipt.Subtotal, receipt.Tax, receipt.Total)
+			}
+			if !reflect.DeepEqual(receipt.Rejected, expected.Rejected) {
+				t.Errorf("expected rejected lines: %v, got rejected lines: %v", expected.Rejected, receipt.Rejected)
+			}
+			gross, err := receipt.Gross()
+			expGross, expErr := expected.Gross()
+			if gross != expGross || err != nil || expErr != nil {
+				t.Errorf("expected gross: %v, got gross: %v, err: %v", expGross, gross, err)
+			}
+			codes := map[string]bool{}
+			for _, line := range expected.Lines {
+				codes[line.Code] = true
+			}
+			if len(receipt.Lines) != len(codes) {
+				t.Errorf("expected %d receipt lines, got %d", len(codes), len(receipt.Lines))
+			}
+		})
+	}
+}
+
+// Test_Pricer tests a Pricer can price the lines added so far more than once, and fails on the first invalid line unless collecting errors.
+func Test_Pricer(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+		"B": {Price: 35, OfferQuantity: 2, OfferPrice: 60},
+	}
+
+	pricer := checkout.NewPricer(products, checkout.PricingOptions{})
+	for _, cL := range []checkout.CheckoutLine{{Code: "A", Quantity: 2}, {Code: "B", Quantity: 2}, {Code: "A", Quantity: 1}} {
+		if err := pricer.Add(cL); err != nil {
+			t.Fatalf("expected no error, got err: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		receipt, err := pricer.Receipt()
+		if err != nil || receipt.Total != gbp(200) {
+			t.Errorf("expected checkout price of: 200, got checkout price of: %v, err: %v", receipt.Total, err)
+		}
+		expLines := []checkout.ReceiptLine{
+			{Code: "A", Quantity: 3, UnitPrice: gbp(50), Gross: gbp(150)},
+			{Code: "B", Quantity: 2, UnitPrice: gbp(35), Gross: gbp(70)},
+		}
+		if !reflect.DeepEqual(receipt.Lines, expLines) {
+			t.Errorf("expected lines: %+v, got lines: %+v", expLines, receipt.Lines)
+		}
+	}
+
+	// check the pricer fails on an invalid line
+	if err := pricer.Add(checkout.CheckoutLine{Code: "E", Quantity: 1}); !errors.Is(err, checkout.ErrUnknownProduct) {
+		t.Errorf("expected unknown product error, got err: %v", err)
+	}
+	if err := pricer.Add(checkout.CheckoutLine{Code: "A", Quantity: 1}); !errors.Is(err, checkout.ErrUnknownProduct) {
+		t.Errorf("expected the pricer to have failed, got err: %v", err)
+	}
+	if _, err := pricer.Receipt(); !errors.Is(err, checkout.ErrUnknownProduct) {
+		t.Errorf("expected the pricer to have failed, got err: %v", err)
+	}
+
+	// check the pricer rejects invalid lines when collecting errors
+	pricer = checkout.NewPricer(products, checkout.PricingOptions{CollectErrors: true})
+	for _, cL := range []checkout.CheckoutLine{{Code: "E", Quantity: 1}, {Code: "A", Quantity: 3}, {Code: "B", Quantity: -1}} {
+		pricer.Add(cL)
+	}
+	receipt, err := pricer.Receipt()
+	var lineErrs *checkout.LineErrors
+	if !errors.As(err, &lineErrs) || !reflect.DeepEqual(receipt.Rejected, []int{0, 2}) || receipt.Total != gbp(140) {
+		t.Errorf("expected lines 0 and 2 rejected with a total of 140, got rejected: %v, total: %v, err: %v", receipt.Rejected, receipt.Total, err)
+	}
+
+	// check lines of a product whose pricing rules cannot be built are rejected when collecting errors
+	products["C"] = checkout.Product{Price: 25, OfferQuantity: -1}
+	pricer = checkout.NewPricer(products, checkout.PricingOptions{CollectErrors: true})
+	for _, cL := range []checkout.CheckoutLine{{Code: "C", Quantity: 1}, {Code: "A", Quantity: 3}} {
+		pricer.Add(cL)
+	}
+	receipt, err = pricer.Receipt()
+	if !errors.As(err, &lineErrs) || !reflect.DeepEqual(receipt.Rejected, []int{0}) || receipt.Total != gbp(140) {
+		t.Errorf("expected line 0 rejected with a total of 140, got rejected: %v, total: %v, err: %v", receipt.Rejected, receipt.Total, err)
+	}
+}
+
+// benchmarkCheckout returns a checkout JSON array of n checkout lines, cycling through the example products.
+func benchmarkCheckout(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+		fmt.Fprintf(&buf, "    {\"code\": %q, \"quantity\": %d}", string(rune('A'+i%4)), 1+i%3)
+	}
+	buf.WriteString("\n]\n")
+	return buf.Bytes()
+}
+
+// benchmarkProducts decodes the example products.
+func benchmarkProducts(b *testing.B) map[string]checkout.Product {
+	products, err := checkout.DecodeProductData("../testdata/product_sets/1.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return products
+}
+
+// Benchmark_DecodeAndPriceCheckout benchmarks decoding a large checkout into memory with DecodeCheckoutReader, then pricing it with PriceCheckout.
+func Benchmark_DecodeAndPriceCheckout(b *testing.B) {
+	data := benchmarkCheckout(100000)
+	products := benchmarkProducts(b)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cLSlice, err := checkout.DecodeCheckoutReader(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := checkout.PriceCheckout(cLSlice, products, checkout.PricingOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmark_StreamCheckout benchmarks decoding and pricing a large checkout one line at a time with StreamCheckout.
+func Benchmark_StreamCheckout(b *testing.B) {
+	data := benchmarkCheckout(100000)
+	products := benchmarkProducts(b)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := checkout.StreamCheckout(bytes.NewReader(data), products, checkout.PricingOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
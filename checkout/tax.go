@@ -0,0 +1,368 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Tax classes of a Product, each taxed at the rate of the class in a TaxTable.
+// Products in the zero class are taxable at 0% and shown in the tax breakdown, exempt products are not taxable at all.
+const (
+	TaxClassStandard = "standard"
+	TaxClassReduced  = "reduced"
+	TaxClassZero     = "zero"
+	TaxClassExempt   = "exempt"
+)
+
+// Tax rounding modes of a TaxTable.
+const (
+	// TaxRoundingLine calculates and rounds the tax of each product code in the basket, with the rounded amounts added to give the tax of each class.
+	// Checkout lines of the same product code are merged before pricing, so their tax is rounded once.
+	TaxRoundingLine = "line"
+	// TaxRoundingInvoice adds the net amounts of each product in a class, with the tax of the class calculated and rounded once.
+	TaxRoundingInvoice = "invoice"
+)
+
+// MaxTaxRate is the largest TaxRate of a TaxTable, being 100%.
+const MaxTaxRate TaxRate = 10000
+
+// taxClasses lists the tax classes in the order they appear in the tax breakdown of a Receipt
+var taxClasses = []string{TaxClassStandard, TaxClassReduced, TaxClassZero, TaxClassExempt}
+
+type (
+	// TaxTable holds the tax rate of each tax class, and how the tax on a checkout is calculated.
+	//
+	// Rates maps the standard, reduced and zero tax classes to their TaxRate, with the zero class defaulting to 0%.
+	// If Inclusive is true the prices of products already include tax, which is then broken out of the total,
+	// otherwise tax is added to the total.
+	// Rounding is either TaxRoundingLine (the default) or TaxRoundingInvoice, with amounts of tax rounded to the nearest minor unit,
+	// and halves rounded away from zero.
+	//
+	// DecodeTaxTable (io.go) returns a TaxTable from a JSON file, e.g. {"Rates": {"standard": 20, "reduced": 5}, "Inclusive": true}.
+	TaxTable struct {
+		Rates     map[string]TaxRate
+		Inclusive bool
+		Rounding  string
+	}
+
+	// TaxRate is a tax rate in hundredths of a percent (e.g. 2000 is 20%), given in JSON as a decimal percentage (e.g. 20 or 7.7).
+	TaxRate int64
+
+	// TaxTotal stores the tax on the products of a tax class in a checkout.
+	//
+	// Taxable is the price of the products in the class after adjustments, including tax if prices are tax inclusive,
+	// with Tax being the tax on that amount at Rate.
+	TaxTotal struct {
+		Class   string
+		Rate    TaxRate
+		Taxable Money
+		Tax     Money
+	}
+)
+
+// UnmarshalJSON decodes a TaxRate from a JSON decimal percentage, given as a number or string, with at most 2 decimal places.
+// A null leaves the rate unchanged, as for other types.
+func (r *TaxRate) UnmarshalJSON(data []byte) error {
+
+	if string(data) == "null" {
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+
+	rate, err := parseDecimal(number.String(), 2)
+	if err != nil {
+		return fmt.Errorf("tax rate: %w", err)
+	}
+	*r = TaxRate(rate)
+
+	return nil
+}
+
+// String formats the rate as a percentage (e.g. "20%" or "7.7%").
+func (r TaxRate) String() string {
+	percent := strconv.FormatInt(int64(r)/100, 10)
+	if fraction := int64(r) % 100; fraction != 0 {
+		if fraction < 0 {
+			fraction = -fraction
+		}
+		percent += "." + strings.TrimRight(fmt.Sprintf("%02d", fraction), "0")
+	}
+	return percent + "%"
+}
+
+// Validate checks the TaxTable is valid, returning an error if a rate is given for an unknown or exempt class,
+// a rate is negative or more than MaxTaxRate, the zero class has a rate other than 0%, or the rounding mode is unknown.
+func (t TaxTable) Validate() error {
+
+	for class, rate := range t.Rates {
+		if !validTaxClass(class) || class == TaxClassExempt {
+			return fmt.Errorf("cannot give a tax rate for tax class %q", class)
+		}
+		if rate < 0 {
+			return fmt.Errorf("tax rate of tax class %q cannot be negative", class)
+		}
+		if rate > MaxTaxRate {
+			return fmt.Errorf("tax rate of tax class %q cannot be more than %v", class, MaxTaxRate)
+		}
+		if class == TaxClassZero && rate != 0 {
+			return errors.New("tax rate of the zero tax class must be 0%")
+		}
+	}
+
+	if t.Rounding != "" && t.Rounding != TaxRoundingLine && t.Rounding != TaxRoundingInvoice {
+		return fmt.Errorf("unknown tax rounding %q, must be %q or %q", t.Rounding, TaxRoundingLine, TaxRoundingInvoice)
+	}
+
+	return nil
+}
+
+// validTaxClass returns whether or not class is a tax class, an empty class being the standard class.
+func validTaxClass(class string) bool {
+	if class == "" {
+		return true
+	}
+	for _, c := range taxClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// taxClass returns the tax class of the product, or TaxClassStandard if not given.
+func (p Product) taxClass() string {
+	if p.TaxClass == "" {
+		return TaxClassStandard
+	}
+	return p.TaxClass
+}
+
+// validateTaxClass checks the tax class of the product is one of the tax classes.
+func (p Product) validateTaxClass() error {
+	if !validTaxClass(p.TaxClass) {
+		return fmt.Errorf("unknown tax class %q", p.TaxClass)
+	}
+	return nil
+}
+
+// rate returns the TaxRate of a tax class, the zero class defaulting to 0%.
+//
+// An error is returned if the class is not exempt and has no rate in the table.
+func (t TaxTable) rate(class string) (TaxRate, error) {
+	rate, ok := t.Rates[class]
+	if !ok && class != TaxClassZero {
+		return 0, fmt.Errorf("no tax rate for tax class %q", class)
+	}
+	return rate, nil
+}
+
+// tax returns the tax on an amount at rate, rounded to the nearest minor unit with halves rounded away from zero.
+// If inclusive, the amount includes the tax which is broken out of it, otherwise the tax is added to the amount.
+//
+// false is returned if the tax cannot be represented as an int64, which a rate of at most MaxTaxRate does not allow.
+func (t TaxTable) tax(amount int64, rate TaxRate) (int64, bool) {
+
+	numerator := new(big.Int).Mul(big.NewInt(amount), big.NewInt(int64(rate)))
+	denominator := big.NewInt(10000)
+	if t.Inclusive {
+		denominator.Add(denominator, big.NewInt(int64(rate)))
+	}
+
+	// round half away from zero, by adding half the denominator to the magnitude before truncating
+	half := new(big.Int).Quo(denominator, big.NewInt(2))
+	if numerator.Sign() < 0 {
+		numerator.Sub(numerator, half)
+	} else {
+		numerator.Add(numerator, half)
+	}
+
+	tax := numerator.Quo(numerator, denominator)
+	if !tax.IsInt64() {
+		return 0, false
+	}
+	return tax.Int64(), true
+}
+
+// applyTax calculates the tax on a priced receipt, returning the tax of each tax class in the basket and their total.
+//
+// The net amount of each product in the basket is its gross price, with the amount of each pricing rule adjustment
+// divided between the products it claimed in proportion to their gross price, and the amount of each basket adjustment
+// divided in proportion to their net amounts. Exempt products are not taxed.
+func applyTax(basket *Basket, receipt Receipt, table TaxTable) ([]TaxTotal, Money, error) {
+
+	if err := table.Validate(); err != nil {
+		return nil, Money{}, err
+	}
+
+	net, err := netAmounts(basket, receipt)
+	if err != nil {
+		return nil, Money{}, err
+	}
+
+	var c checked
+	taxable := map[string]int64{}
+	lineTax := map[string]int64{}
+	rates := map[string]TaxRate{}
+	firstCode := map[string]string{}
+
+	for _, code := range basket.Codes() {
+		prod, _ := basket.Product(code)
+		class := prod.taxClass()
+		if class == TaxClassExempt {
+			continue
+		}
+
+		rate, err := table.rate(class)
+		if err != nil {
+			return nil, Money{}, fmt.Errorf("product %q: %w", code, err)
+		}
+		if _, ok := rates[class]; !ok {
+			firstCode[class] = code
+		}
+		rates[class] = rate
+
+		tax, ok := table.tax(net[code], rate)
+		taxable[class] = c.add(taxable[class], net[code])
+		lineTax[class] = c.add(lineTax[class], tax)
+		if !ok {
+			return nil, Money{}, basket.overflowError(code, "tax")
+		}
+		if c.overflow {
+			return nil, Money{}, basket.overflowError(code, "taxable amount")
+		}
+	}
+
+	taxes := []TaxTotal{}
+	total := basket.money(0)
+
+	for _, class := range taxClasses {
+		rate, ok := rates[class]
+		if !ok {
+			continue
+		}
+
+		tax := lineTax[class]
+		if table.Rounding == TaxRoundingInvoice {
+			var ok bool
+			if tax, ok = table.tax(taxable[class], rate); !ok {
+				return nil, Money{}, basket.overflowError(firstCode[class], "tax")
+			}
+		}
+
+		taxes = append(taxes, TaxTotal{Class: class, Rate: rate, Taxable: basket.money(taxable[class]), Tax: basket.money(tax)})
+		if total, err = total.Add(basket.money(tax)); err != nil {
+			return nil, Money{}, basket.overflowError(firstCode[class], "tax")
+		}
+	}
+
+	return taxes, total, nil
+}
+
+// netAmounts returns the price of each product code in the basket after the adjustments of a receipt.
+func netAmounts(basket *Basket, receipt Receipt) (map[string]int64, error) {
+
+	net := map[string]int64{}
+	for _, code := range basket.Codes() {
+		net[code] = basket.gross[code]
+	}
+
+	// divide the amount of each adjustment between the products it claimed,
+	// in proportion to the gross price of the claimed items for pricing rules, and to the net amounts so far for basket rules
+	divide := func(adj Adjustment, basketRule bool) error {
+
+		codes := []string{}
+		weights := []int64{}
+		for _, code := range basket.Codes() {
+			qty, ok := adj.Claimed[code]
+			if len(adj.Claimed) > 0 && !ok {
+				continue
+			}
+			prod, _ := basket.Product(code)
+			weight := int64(qty) * int64(prod.Price)
+			if len(adj.Claimed) == 0 {
+				weight = basket.gross[code]
+			}
+			if basketRule {
+				weight = net[code]
+			}
+			codes = append(codes, code)
+			weights = append(weights, weight)
+		}
+
+		var c checked
+		for i, share := range divideAmount(adj.Amount.Amount, weights) {
+			net[codes[i]] = c.add(net[codes[i]], share)
+			if c.overflow {
+				return basket.overflowError(codes[i], "net amount")
+			}
+		}
+		return nil
+	}
+
+	for _, adj := range receipt.Adjustments {
+		if err := divide(adj, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, adj := range receipt.BasketAdjustments {
+		if err := divide(adj, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return net, nil
+}
+
+// divideAmount divides an amount into shares in proportion to weights, with the shares adding up to the amount.
+//
+// Each share is truncated toward zero, with the remaining minor units given one at a time to the first shares.
+// If the weights are not all positive, the amount is divided equally. If there are no weights, no shares are returned.
+func divideAmount(amount int64, weights []int64) []int64 {
+
+	if len(weights) == 0 {
+		return nil
+	}
+
+	total := big.NewInt(0)
+	for _, weight := range weights {
+		if weight <= 0 {
+			total = nil
+			break
+		}
+		total.Add(total, big.NewInt(weight))
+	}
+
+	shares := make([]int64, len(weights))
+	remainder := amount
+
+	for i, weight := range weights {
+		if total == nil {
+			shares[i] = amount / int64(len(weights))
+		} else {
+			share := new(big.Int).Mul(big.NewInt(amount), big.NewInt(weight))
+			shares[i] = share.Quo(share, total).Int64()
+		}
+		remainder -= shares[i]
+	}
+
+	// give the remainder to the first shares, which is less than the number of shares
+	for i := 0; remainder != 0; i++ {
+		if remainder > 0 {
+			shares[i]++
+			remainder--
+		} else {
+			shares[i]--
+			remainder++
+		}
+	}
+
+	return shares
+}
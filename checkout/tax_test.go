@@ -0,0 +1,232 @@
+package checkout_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_PriceCheckoutTax tests the tax of each tax class calculated by PriceCheckout,
+// for tax exclusive and inclusive prices, rounded per line and per invoice.
+func Test_PriceCheckoutTax(t *testing.T) {
+	products := map[string]checkout.Product{
+		"A": {Price: 50, OfferQuantity: 3, OfferPrice: 140},
+		"B": {Price: 35, OfferQuantity: 2, OfferPrice: 60, TaxClass: checkout.TaxClassReduced},
+		"C": {Price: 25, TaxClass: checkout.TaxClassZero},
+		"D": {Price: 12, TaxClass: checkout.TaxClassExempt},
+		"E": {Price: 13},
+		"F": {Price: 13, TaxClass: checkout.TaxClassStandard},
+	}
+	rates := map[string]checkout.TaxRate{checkout.TaxClassStandard: 2000, checkout.TaxClassReduced: 500}
+
+	testCases := []struct {
+		name          string
+		checkoutLines []checkout.CheckoutLine
+		opts          checkout.PricingOptions
+		expTaxes      []checkout.TaxTotal
+		expTax        int64
+		expTotal      int64
+		expErr        bool
+	}{
+		{
+			"1: tax exclusive, rounded per line",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 3}, {Code: "C", Quantity: 1}, {Code: "D", Quantity: 2}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: rates}},
+			[]checkout.TaxTotal{
+				{Class: "standard", Rate: 2000, Taxable: gbp(140), Tax: gbp(28)},
+				{Class: "reduced", Rate: 500, Taxable: gbp(95), Tax: gbp(5)},
+				{Class: "zero", Rate: 0, Taxable: gbp(25), Tax: gbp(0)},
+			},
+			33,
+			317,
+			false,
+		},
+		{
+			"2: tax inclusive, rounded per invoice",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 3}, {Code: "C", Quantity: 1}, {Code: "D", Quantity: 2}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: rates, Inclusive: true, Rounding: checkout.TaxRoundingInvoice}},
+			[]checkout.TaxTotal{
+				{Class: "standard", Rate: 2000, Taxable: gbp(140), Tax: gbp(23)},
+				{Class: "reduced", Rate: 500, Taxable: gbp(95), Tax: gbp(5)},
+				{Class: "zero", Rate: 0, Taxable: gbp(25), Tax: gbp(0)},
+			},
+			28,
+			284,
+			false,
+		},
+		{
+			"3: basket adjustment divided between tax classes",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 3}, {Code: "B", Quantity: 3}, {Code: "C", Quantity: 1}, {Code: "D", Quantity: 2}},
+			checkout.PricingOptions{
+				BasketRules: []checkout.RuleSpec{rule("spendamountoff", `{"Threshold": 200, "Amount": 20}`)},
+				Tax:         &checkout.TaxTable{Rates: rates},
+			},
+			[]checkout.TaxTotal{
+				{Class: "standard", Rate: 2000, Taxable: gbp(130), Tax: gbp(26)},
+				{Class: "reduced", Rate: 500, Taxable: gbp(88), Tax: gbp(4)},
+				{Class: "zero", Rate: 0, Taxable: gbp(23), Tax: gbp(0)},
+			},
+			30,
+			294,
+			false,
+		},
+		{
+			"4: rounded per line",
+			[]checkout.CheckoutLine{{Code: "E", Quantity: 1}, {Code: "F", Quantity: 1}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: 1000}}},
+			[]checkout.TaxTotal{{Class: "standard", Rate: 1000, Taxable: gbp(26), Tax: gbp(2)}},
+			2,
+			28,
+			false,
+		},
+		{
+			"5: rounded per invoice",
+			[]checkout.CheckoutLine{{Code: "E", Quantity: 1}, {Code: "F", Quantity: 1}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: 1000}, Rounding: checkout.TaxRoundingInvoice}},
+			[]checkout.TaxTotal{{Class: "standard", Rate: 1000, Taxable: gbp(26), Tax: gbp(3)}},
+			3,
+			29,
+			false,
+		},
+		{
+			"6: exempt products only",
+			[]checkout.CheckoutLine{{Code: "D", Quantity: 2}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: rates}},
+			[]checkout.TaxTotal{},
+			0,
+			24,
+			false,
+		},
+		{
+			"7: no rate for tax class",
+			[]checkout.CheckoutLine{{Code: "B", Quantity: 1}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: 2000}}},
+			nil,
+			0,
+			0,
+			true,
+		},
+		{
+			"8: invalid tax table",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: rates, Rounding: "nearest"}},
+			nil,
+			0,
+			0,
+			true,
+		},
+		{
+			"9: tax rate above 100%",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: checkout.MaxTaxRate + 1}}},
+			nil,
+			0,
+			0,
+			true,
+		},
+		{
+			"10: tax rate of 100%",
+			[]checkout.CheckoutLine{{Code: "A", Quantity: 1}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: checkout.MaxTaxRate}}},
+			[]checkout.TaxTotal{{Class: "standard", Rate: 10000, Taxable: gbp(50), Tax: gbp(50)}},
+			50,
+			100,
+			false,
+		},
+		{
+			"11: line rounding rounds once for lines of the same product",
+			[]checkout.CheckoutLine{{Code: "E", Quantity: 1}, {Code: "E", Quantity: 1}},
+			checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: map[string]checkout.TaxRate{checkout.TaxClassStandard: 1000}}},
+			[]checkout.TaxTotal{{Class: "standard", Rate: 1000, Taxable: gbp(26), Tax: gbp(3)}},
+			3,
+			29,
+			false,
+		},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			receipt, err := checkout.PriceCheckout(testCase.checkoutLines, products, testCase.opts)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Fatalf("expected error: %v, got err: %v", testCase.expErr, err)
+			}
+			if err != nil {
+				return
+			}
+			// compare taxes and totals to expected vals
+			if !reflect.DeepEqual(receipt.Taxes, testCase.expTaxes) {
+				t.Errorf("expected taxes: %+v, got taxes: %+v", testCase.expTaxes, receipt.Taxes)
+			}
+			if receipt.Tax != gbp(testCase.expTax) {
+				t.Errorf("expected tax of: %d, got tax of: %v", testCase.expTax, receipt.Tax)
+			}
+			if receipt.Total != gbp(testCase.expTotal) {
+				t.Errorf("expected checkout price of: %d, got checkout price of: %v", testCase.expTotal, receipt.Total)
+			}
+		})
+	}
+}
+
+// Test_PriceCheckoutTaxOverflow tests an *OverflowError naming the product is returned when adding the tax to a checkout overflows,
+// with both line and invoice rounding.
+func Test_PriceCheckoutTaxOverflow(t *testing.T) {
+	checkoutLines := []checkout.CheckoutLine{{Code: "A", Quantity: 1}}
+	products := map[string]checkout.Product{"A": {Price: math.MaxInt64/2 + 1}}
+	rates := map[string]checkout.TaxRate{checkout.TaxClassStandard: checkout.MaxTaxRate}
+
+	for _, rounding := range []string{checkout.TaxRoundingLine, checkout.TaxRoundingInvoice} {
+		_, err := checkout.PriceCheckout(checkoutLines, products, checkout.PricingOptions{Tax: &checkout.TaxTable{Rates: rates, Rounding: rounding}})
+		var overflowErr *checkout.OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Code != "A" || overflowErr.Amount != "total" {
+			t.Errorf("expected total overflow of product A with %s rounding, got err: %v", rounding, err)
+		}
+	}
+}
+
+// Test_TaxRate tests decoding a TaxRate from a JSON decimal percentage, and formatting it as a percentage.
+func Test_TaxRate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		data      string
+		expected  checkout.TaxRate
+		expString string
+		expErr    bool
+	}{
+		{"1: whole percentage", `20`, 2000, "20%", false},
+		{"2: decimal percentage", `7.7`, 770, "7.7%", false},
+		{"3: two decimal places", `"2.55"`, 255, "2.55%", false},
+		{"4: zero", `0`, 0, "0%", false},
+		{"5: too many decimal places", `7.775`, 0, "", true},
+		{"6: not a number", `"twenty"`, 0, "", true},
+		{"7: null", `null`, 0, "0%", false},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			var rate checkout.TaxRate
+			err := json.Unmarshal([]byte(testCase.data), &rate)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %v", testCase.expErr, err)
+			}
+			if err == nil && (rate != testCase.expected || rate.String() != testCase.expString) {
+				t.Errorf("expected rate: %d (%s), got rate: %d (%s)", testCase.expected, testCase.expString, rate, rate)
+			}
+		})
+	}
+
+	// check null leaves a rate unchanged
+	rate := checkout.TaxRate(2000)
+	if err := json.Unmarshal([]byte(`null`), &rate); err != nil || rate != 2000 {
+		t.Errorf("expected null to leave rate 2000, got rate: %d, err: %v", rate, err)
+	}
+}
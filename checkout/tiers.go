@@ -0,0 +1,115 @@
+package checkout
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tier modes select how a tiered pricing table prices the items of a product.
+const (
+	// TierModeVolume prices every item at the price of the highest tier reached (e.g. 12 items at 45 each).
+	TierModeVolume = "volume"
+
+	// TierModeGraduated prices the items in each band at the price of that band (e.g. 9 items at 50 each, and 3 items at 45 each).
+	TierModeGraduated = "graduated"
+)
+
+// validateTiers checks the tiered pricing table of a Product.
+//
+// An error is returned if the tier mode is unknown, a MinQuantity is not positive, the tiers are not in order of increasing MinQuantity,
+// or the product also has an offer.
+func (p Product) validateTiers() error {
+
+	if p.TierMode != "" && p.TierMode != TierModeVolume && p.TierMode != TierModeGraduated {
+		return fmt.Errorf("unknown tier mode %q, expected %q or %q", p.TierMode, TierModeVolume, TierModeGraduated)
+	}
+	if len(p.Tiers) > 0 && p.OfferQuantity != 0 {
+		return errors.New("tiers cannot be used with an offer quantity")
+	}
+
+	for i, tier := range p.Tiers {
+		if tier.MinQuantity <= 0 {
+			return fmt.Errorf("tier %d: min quantity must be positive", i)
+		}
+		if i > 0 && tier.MinQuantity <= p.Tiers[i-1].MinQuantity {
+			return fmt.Errorf("tier %d: min quantity must be more than the min quantity of the previous tier", i)
+		}
+	}
+
+	return nil
+}
+
+// tieredPrice returns the price of quantity items of a product, using its tiered pricing table,
+// with any overflow recorded by c.
+func (p Product) tieredPrice(quantity int, c *checked) int64 {
+
+	if p.TierMode == TierModeGraduated {
+		total := int64(0)
+		price := p.Price
+		from := 1
+
+		// price the items of each band below quantity
+		for _, tier := range p.Tiers {
+			if tier.MinQuantity > quantity {
+				break
+			}
+			total = c.add(total, c.mul(int64(tier.MinQuantity-from), int64(price)))
+			price = tier.Price
+			from = tier.MinQuantity
+		}
+
+		return c.add(total, c.mul(int64(quantity-from+1), int64(price)))
+	}
+
+	// price all items at the highest tier reached
+	price := p.Price
+	for _, tier := range p.Tiers {
+		if tier.MinQuantity > quantity {
+			break
+		}
+		price = tier.Price
+	}
+
+	return c.mul(int64(quantity), int64(price))
+}
+
+// tiersRule prices the remaining items of a product using its tiered pricing table.
+type tiersRule struct {
+	Code  string
+	Mode  string
+	Tiers []Tier
+}
+
+// Apply claims every remaining item of the product in a single application, with the adjustment being the difference
+// between the tiered price and the normal price of those items.
+func (r tiersRule) Apply(basket *Basket, limit int) (Adjustment, error) {
+
+	prod, ok := basket.Product(r.Code)
+	n := basket.Remaining(r.Code)
+	if !ok || n == 0 || limit == 0 {
+		return Adjustment{}, nil
+	}
+
+	var c checked
+	amount := c.sub(prod.tieredPrice(n, &c), c.mul(int64(n), int64(prod.Price)))
+	if c.overflow {
+		return Adjustment{}, basket.overflowError(r.Code, "tiered price")
+	}
+
+	if err := basket.Claim(r.Code, n); err != nil {
+		return Adjustment{}, err
+	}
+
+	mode := r.Mode
+	if mode == "" {
+		mode = TierModeVolume
+	}
+
+	return Adjustment{
+		Rule:         "tiers",
+		Description:  fmt.Sprintf("%s pricing %s", mode, r.Code),
+		Applications: 1,
+		Claimed:      map[string]int{r.Code: n},
+		Amount:       basket.money(amount),
+	}, nil
+}
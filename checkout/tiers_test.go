@@ -0,0 +1,55 @@
+package checkout_test
+
+import (
+	"testing"
+
+	"github.com/billiem/checkout-system/checkout"
+)
+
+// Test_TieredPricing tests tiered pricing tables through the GetCheckoutPrice function.
+//
+// It passes a map of [productCode]Product with tiers and a slice of CheckoutLine to the function,
+// checking for the correct expected checkout price, and whether or not an error is expected.
+func Test_TieredPricing(t *testing.T) {
+	tiers := []checkout.Tier{{MinQuantity: 10, Price: 45}, {MinQuantity: 50, Price: 40}}
+
+	testCases := []struct {
+		name     string
+		quantity int
+		product  checkout.Product
+		expected int
+		expErr   bool
+	}{
+		{"1: volume below first tier", 9, checkout.Product{Price: 50, Tiers: tiers}, 450, false},
+		{"2: volume at first tier", 10, checkout.Product{Price: 50, Tiers: tiers}, 450, false},
+		{"3: volume between tiers", 49, checkout.Product{Price: 50, Tiers: tiers, TierMode: checkout.TierModeVolume}, 2205, false},
+		{"4: volume above last tier", 60, checkout.Product{Price: 50, Tiers: tiers}, 2400, false},
+		{"5: graduated below first tier", 9, checkout.Product{Price: 50, Tiers: tiers, TierMode: checkout.TierModeGraduated}, 450, false},
+		{"6: graduated between tiers", 12, checkout.Product{Price: 50, Tiers: tiers, TierMode: checkout.TierModeGraduated}, 585, false},
+		{"7: graduated above last tier", 60, checkout.Product{Price: 50, Tiers: tiers, TierMode: checkout.TierModeGraduated}, 2690, false},
+		{"8: graduated first tier from 1", 3, checkout.Product{Price: 50, Tiers: []checkout.Tier{{1, 30}, {2, 20}}, TierMode: checkout.TierModeGraduated}, 70, false},
+		{"9: unknown tier mode", 3, checkout.Product{Price: 50, Tiers: tiers, TierMode: "fake"}, 0, true},
+		{"10: tiers out of order", 3, checkout.Product{Price: 50, Tiers: []checkout.Tier{{10, 45}, {5, 40}}}, 0, true},
+		{"11: tier with 0 min quantity", 3, checkout.Product{Price: 50, Tiers: []checkout.Tier{{0, 45}}}, 0, true},
+		{"12: tiers with offer", 3, checkout.Product{Price: 50, Tiers: tiers, OfferQuantity: 3, OfferPrice: 140}, 0, true},
+	}
+
+	// loop over and run test cases
+	for _, testCase := range testCases {
+		// run subtest for each testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := checkout.GetCheckoutPrice(
+				[]checkout.CheckoutLine{{Code: "A", Quantity: testCase.quantity}},
+				map[string]checkout.Product{"A": testCase.product},
+			)
+			// check if err expected
+			if (err != nil) != testCase.expErr {
+				t.Errorf("expected error: %v, got err: %s", testCase.expErr, err)
+			}
+			// compare result val to expected val
+			if result != testCase.expected {
+				t.Errorf("expected checkout price of: %v, got checkout price of: %v", testCase.expected, result)
+			}
+		})
+	}
+}
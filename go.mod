@@ -1,3 +1,9 @@
 module github.com/billiem/checkout-system
 
-go 1.16
+go 1.17
+
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/klauspost/compress v1.15.9
+	gopkg.in/yaml.v3 v3.0.1
+)
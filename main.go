@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/billiem/checkout-system/checkout"
@@ -9,7 +10,9 @@ import (
 func main() {
 	err := checkout.CheckoutCLI(os.Stdout)
 	if err != nil {
-		panic(err)
+		// print errors located in a file as file:line:column: message
+		fmt.Fprintln(os.Stderr, checkout.FormatError(err))
+		os.Exit(1)
 	}
 }
 